@@ -1,7 +1,10 @@
 package caddycfg
 
+import "reflect"
+
 type argumentAccess interface {
 	appendData(items []string)
+	setHead(head string)
 	Arguments() []string
 }
 
@@ -15,6 +18,7 @@ type argumentAccess interface {
 // implement argumentAccess – this is equivalent for having type Args embedded or being type Args itself. Although
 // it is possible to use Args itself, it will not work well enough. So, use it for embedding into your own types
 type Args struct {
+	head string
 	data []string
 }
 
@@ -27,10 +31,31 @@ func (a *Args) appendData(items []string) {
 	copy(a.data, items)
 }
 
+// NewArgs creates Args with the given head and arguments, e.g. for slice items to be marshaled
+func NewArgs(head string, args ...string) Args {
+	var a Args
+	a.setHead(head)
+	a.appendData(args)
+	return a
+}
+
+func (a *Args) setHead(head string) {
+	a.head = head
+}
+
 func (a *Args) Arguments() []string {
 	return a.data
 }
 
+// Head returns the head of a slice item, e.g. route for
+//     route /api {
+//         …
+//     }
+// in a block of a slice of structs. It is empty for other values
+func (a *Args) Head() string {
+	return a.head
+}
+
 // deprecated ArgumentsCollector special interface whose implementations can be used for taking arguments with additional control
 // over the content, e.g. they can keep context to provide valuable error diagnostic.
 // Function AppendArgument will be used to consume positional parameters in a right order and Arguments is to be used
@@ -46,3 +71,32 @@ type ArgumentsConsumer interface {
 	ConsumeArguments(head Token, args []Token) error
 	Arguments() []string
 }
+
+// isHeadedType checks if items of type t have heads: t must be a struct and either the slice field is tagged with
+// heads option or t has a field keeping the head. Pointer types are dereferenced to check
+func isHeadedType(t reflect.Type, tagName string, heads bool) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	if heads {
+		return true
+	}
+	index, err := findHeadField(t, tagName)
+	return err == nil && index != nil
+}
+
+// setItemHead keeps the head of a decoded slice item if it embeds Args or has a field tagged with head option
+func setItemHead(v reflect.Value, head string, tagName string) {
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if a, ok := v.Addr().Interface().(argumentAccess); ok {
+		a.setHead(head)
+	}
+	if index, _ := findHeadField(v.Type(), tagName); index != nil {
+		v.FieldByIndex(index).SetString(head)
+	}
+}
@@ -0,0 +1,84 @@
+package caddycfg
+
+import (
+	"io/ioutil"
+)
+
+// columnResolver sets columns of tokens coming from caddy.Controller, which only gives their lines. It tokenizes source
+// files of tokens and matches tokens by their lines and values
+type columnResolver struct {
+	readFile func(name string) ([]byte, error)
+	files    map[string]*fileColumns
+}
+
+// fileColumns tokens of a file by lines. Cursors point to the first token of a line not matched yet, since equal
+// tokens on the same line are matched in order
+type fileColumns struct {
+	lines   map[int][]Token
+	cursors map[int]int
+}
+
+func newColumnResolver(readFile func(name string) ([]byte, error)) *columnResolver {
+	if readFile == nil {
+		readFile = ioutil.ReadFile
+	}
+	return &columnResolver{
+		readFile: readFile,
+		files:    map[string]*fileColumns{},
+	}
+}
+
+// resolve sets Col and EndCol of the token, they are left intact if the token cannot be found in the source
+func (r *columnResolver) resolve(t *Token) {
+	fc := r.file(t.File)
+	if fc == nil {
+		return
+	}
+	tokens := fc.lines[t.Lin]
+	for i := fc.cursors[t.Lin]; i < len(tokens); i++ {
+		if tokens[i].Value == t.Value {
+			t.Col = tokens[i].Col
+			t.EndCol = tokens[i].EndCol
+			fc.cursors[t.Lin] = i + 1
+			return
+		}
+	}
+}
+
+// lookup sets Col and EndCol of the token to ones of the first token of its line with the same value, they are left
+// intact if there is no such token in the source
+func (r *columnResolver) lookup(t *Token) {
+	fc := r.file(t.File)
+	if fc == nil {
+		return
+	}
+	for _, token := range fc.lines[t.Lin] {
+		if token.Value == t.Value {
+			t.Col = token.Col
+			t.EndCol = token.EndCol
+			return
+		}
+	}
+}
+
+// file returns tokens of the file, it is nil if the file cannot be read or tokenized
+func (r *columnResolver) file(name string) *fileColumns {
+	if fc, ok := r.files[name]; ok {
+		return fc
+	}
+
+	var fc *fileColumns
+	if data, err := r.readFile(name); err == nil {
+		if tokens, err := tokenize(name, data); err == nil {
+			fc = &fileColumns{
+				lines:   map[int][]Token{},
+				cursors: map[int]int{},
+			}
+			for _, token := range tokens {
+				fc.lines[token.Lin] = append(fc.lines[token.Lin], token)
+			}
+		}
+	}
+	r.files[name] = fc
+	return fc
+}
@@ -0,0 +1,84 @@
+package caddycfg
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/caddyserver/caddy"
+)
+
+// Decoder decodes plugin config with a policy set up with options
+type Decoder struct {
+	stream Stream
+	head   Token
+	opts   options
+}
+
+// NewDecoder creates a decoder of c config
+func NewDecoder(c *caddy.Controller, opts ...Option) *Decoder {
+	d := NewStreamDecoder(nil, opts...)
+	var columns *columnResolver
+	if d.opts.resolveColumns {
+		columns = newColumnResolver(nil)
+	}
+	d.stream = newStream(c, columns)
+	return d
+}
+
+// NewTokensDecoder creates a decoder of config tokens, they are expected to be produced with Caddyfile lexer
+// semantics, i.e. tokens of a line must have the same File and Lin
+func NewTokensDecoder(tokens []Token, opts ...Option) *Decoder {
+	return NewStreamDecoder(NewSliceStream(tokens), opts...)
+}
+
+// NewStreamDecoder creates a decoder of the stream tokens, the first one is taken as a head
+func NewStreamDecoder(s Stream, opts ...Option) *Decoder {
+	return &Decoder{
+		stream: s,
+		opts:   newOptions(opts),
+	}
+}
+
+// Decode decodes plugin config into dest, which must be a pointer
+func (d *Decoder) Decode(dest interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Type().Kind() != reflect.Ptr {
+		return fmt.Errorf("unmarshal into non-pointer %T", dest)
+	}
+
+	if !d.stream.NextArg() {
+		// plugin name is expected
+		return fmt.Errorf("got no config data for plugin at line %d: %w", d.stream.Token().Lin, ErrNoData)
+	}
+	d.head = d.stream.Token()
+	d.stream.Confirm()
+
+	return d.decode(destValue.Elem(), !d.opts.allowTrailingData)
+}
+
+// decode decodes the entry with already consumed head into v
+func (d *Decoder) decode(v reflect.Value, checkTrailingData bool) error {
+	unmarshaler := &caddyCfgUnmarshaler{
+		headToken: d.head,
+		options:   d.opts,
+	}
+
+	stream := d.stream
+	if d.opts.collectErrors {
+		stream = &depthStream{Stream: stream}
+	}
+	err := unmarshaler.unmarshal(d.head, stream, v)
+	if err == nil && checkTrailingData && stream.Next() {
+		err = tokenErrorf(stream.Token(), KindUnexpectedData, "got unexpected data '%s' for plugin '%s'", stream.Token(), d.head)
+	}
+	if d.opts.collectErrors {
+		return unmarshaler.collected(err)
+	}
+
+	return err
+}
+
+// HeadToken returns token with plugin name, it is only available after Decode call
+func (d *Decoder) HeadToken() Token {
+	return d.head
+}
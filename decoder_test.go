@@ -0,0 +1,154 @@
+package caddycfg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder(t *testing.T) {
+	type (
+		config struct {
+			KeyA int    `json:"key_a"`
+			KeyB string `json:"key_b"`
+		}
+		yamlConfig struct {
+			Key int `json:"json_key" yaml:"yaml_key"`
+		}
+		sample struct {
+			name     string
+			input    string
+			options  []Option
+			target   interface{}
+			expected interface{}
+			wantErr  bool
+		}
+	)
+
+	samples := []sample{
+		{
+			name: "success-default",
+			input: `
+                root {
+                    key_a 1
+                    key_b b
+                }`,
+			target:   &config{},
+			expected: &config{KeyA: 1, KeyB: "b"},
+		},
+		{
+			name: "error-unknown-key",
+			input: `
+                root {
+                    key_a 1
+                    key_c c
+                }`,
+			target:  &config{},
+			wantErr: true,
+		},
+		{
+			name: "success-unknown-keys-allowed",
+			input: `
+                root {
+                    key_c c d
+                    key_a 1
+                    key_d {
+                        a {
+                            b c
+                        }
+                    }
+                    key_b b
+                }`,
+			options:  []Option{AllowUnknownKeys(true)},
+			target:   &config{},
+			expected: &config{KeyA: 1, KeyB: "b"},
+		},
+		{
+			name: "error-trailing-data",
+			input: `
+                root {
+                    key_a 1
+                }
+                other`,
+			target:  &config{},
+			wantErr: true,
+		},
+		{
+			name: "success-trailing-data-allowed",
+			input: `
+                root {
+                    key_a 1
+                }
+                other`,
+			options:  []Option{AllowTrailingData(true)},
+			target:   &config{},
+			expected: &config{KeyA: 1},
+		},
+		{
+			name: "success-duplicate-key-allowed",
+			input: `
+                root {
+                    key_a 1
+                    key_a 2
+                }`,
+			options:  []Option{AllowDuplicateKeys(true)},
+			target:   &config{},
+			expected: &config{KeyA: 2},
+		},
+		{
+			name: "error-duplicate-key",
+			input: `
+                root {
+                    key_a 1
+                    key_a 2
+                }`,
+			target:  &config{},
+			wantErr: true,
+		},
+		{
+			name: "success-tag-name",
+			input: `
+                root {
+                    yaml_key 1
+                }`,
+			options:  []Option{TagName("yaml")},
+			target:   &yamlConfig{},
+			expected: &yamlConfig{Key: 1},
+		},
+		{
+			name: "success-key-normalizer",
+			input: `
+                root {
+                    KEY-A 1
+                    Key_B b
+                }`,
+			options: []Option{KeyNormalizer(func(key string) string {
+				return strings.ToLower(strings.Replace(key, "-", "_", -1))
+			})},
+			target:   &config{},
+			expected: &config{KeyA: 1, KeyB: "b"},
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			c := caddy.NewTestController("http", s.input)
+			d := NewDecoder(c, s.options...)
+			err := d.Decode(s.target)
+			if err != nil {
+				if !s.wantErr {
+					t.Error(err)
+				}
+				return
+			}
+			if err == nil && s.wantErr {
+				t.Errorf("error expected")
+				return
+			}
+			require.Equal(t, s.expected, s.target)
+			require.Equal(t, "root", d.HeadToken().Value)
+		})
+	}
+}
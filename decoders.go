@@ -0,0 +1,175 @@
+package caddycfg
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DecodeFunc decodes a value of a registered type from a single token. The value returned must be assignable to
+// the type
+type DecodeFunc func(t Token) (interface{}, error)
+
+var (
+	decodersLock sync.RWMutex
+	decoders     = map[reflect.Type]DecodeFunc{}
+)
+
+func init() {
+	RegisterDecoder(reflect.TypeOf((*regexp.Regexp)(nil)), func(t Token) (interface{}, error) {
+		return regexp.Compile(t.Value)
+	})
+	RegisterDecoder(reflect.TypeOf(Path("")), func(t Token) (interface{}, error) {
+		return resolvePath(t)
+	})
+	RegisterDecoder(reflect.TypeOf(url.URL{}), func(t Token) (interface{}, error) {
+		u, err := url.Parse(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return *u, nil
+	})
+}
+
+// RegisterDecoder registers decode function for values of type typ for all decoders. The function is used for pointers
+// to typ as well, pointers are allocated then. Registered types are written with their String method by Marshal.
+// Built-in decoders are registered for *regexp.Regexp, url.URL, Path, network addresses and HostPort
+func RegisterDecoder(typ reflect.Type, decode DecodeFunc) {
+	decodersLock.Lock()
+	defer decodersLock.Unlock()
+	decoders[typ] = decode
+}
+
+// RegisterDecoder registers decode function for values of type typ for this decoder only, it takes precedence over
+// ones registered globally
+func (d *Decoder) RegisterDecoder(typ reflect.Type, decode DecodeFunc) {
+	if d.opts.decoders == nil {
+		d.opts.decoders = map[reflect.Type]DecodeFunc{}
+	}
+	d.opts.decoders[typ] = decode
+}
+
+// globalDecoder returns globally registered decode function of type typ
+func globalDecoder(typ reflect.Type) DecodeFunc {
+	decodersLock.RLock()
+	defer decodersLock.RUnlock()
+	return decoders[typ]
+}
+
+// lookupDecoder looks for a decode function of type t or of a type t points to, the type it is registered for is
+// returned as well
+func (c *caddyCfgUnmarshaler) lookupDecoder(t reflect.Type) (reflect.Type, DecodeFunc) {
+	for {
+		if decode, ok := c.decoders[t]; ok {
+			return t, decode
+		}
+		if decode := globalDecoder(t); decode != nil {
+			return t, decode
+		}
+		if t.Kind() != reflect.Ptr {
+			return nil, nil
+		}
+		t = t.Elem()
+	}
+}
+
+func (c *caddyCfgUnmarshaler) processRegistered(s Stream, v reflect.Value, typ reflect.Type, decode DecodeFunc) error {
+	if err := c.needArgValue(s, v); err != nil {
+		return err
+	}
+
+	t := s.Token()
+	res, err := decode(t)
+	if err != nil {
+		if _, ok := err.(*TokenError); ok {
+			return err
+		}
+		return tokenErrorf(t, KindInvalidValue, "cannot unmarshal into %s: %s", typ, err)
+	}
+	value := reflect.ValueOf(res)
+	if !value.IsValid() || !value.Type().AssignableTo(typ) {
+		return tokenErrorf(t, KindUnsupportedType, "decoder of %s returned %T", typ, res)
+	}
+	if err := c.checkValue(res); err != nil {
+		return tokenErrorf(t, KindInvalidValue, "cannot unmarshal into %s: %s", typ, err)
+	}
+
+	for v.Type() != typ {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	v.Set(value)
+	s.Confirm()
+
+	return nil
+}
+
+// checkValue checks the decoded value against options of the current field tag
+func (c *caddyCfgUnmarshaler) checkValue(v interface{}) error {
+	switch v := v.(type) {
+	case url.URL:
+		return checkScheme(c.tag, &v)
+	}
+	return nil
+}
+
+// checkScheme checks if URL scheme is one of listed with scheme tag option, e.g. `caddy:"upstream,scheme=http|https"`.
+// The option with no value only requires the scheme to be set
+func checkScheme(tag fieldTag, u *url.URL) error {
+	schemes, ok := tag.options["scheme"]
+	if !ok {
+		return nil
+	}
+	if schemes == "" {
+		if u.Scheme == "" {
+			return fmt.Errorf("URL %s has no scheme", u)
+		}
+		return nil
+	}
+	allowed := strings.Split(schemes, "|")
+	for _, scheme := range allowed {
+		if strings.EqualFold(scheme, u.Scheme) {
+			return nil
+		}
+	}
+	return fmt.Errorf("scheme of URL %s must be %s", u, strings.Join(allowed, " or "))
+}
+
+// isLeafType checks if values of non-pointer type t are decoded from a single token with a registered decoder or
+// an unmarshaler
+func (c *caddyCfgUnmarshaler) isLeafType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		return false
+	}
+	if _, decode := c.lookupDecoder(t); decode != nil {
+		return true
+	}
+	return implementsUnmarshaler(t, textUnmarshalerType) || implementsUnmarshaler(t, jsonUnmarshalerType)
+}
+
+// isRegisteredType checks if there is a global decoder of type t or of a type t points to
+func isRegisteredType(t reflect.Type) bool {
+	for {
+		if globalDecoder(t) != nil {
+			return true
+		}
+		if t.Kind() != reflect.Ptr {
+			return false
+		}
+		t = t.Elem()
+	}
+}
+
+// registeredString writes a value of registered type with its String method
+func registeredString(ptr reflect.Value) (string, error) {
+	if stringer, ok := ptr.Interface().(fmt.Stringer); ok {
+		return stringer.String(), nil
+	}
+	if stringer, ok := ptr.Elem().Interface().(fmt.Stringer); ok {
+		return stringer.String(), nil
+	}
+	return "", fmt.Errorf("marshal of %s is not supported as it has no String method", ptr.Elem().Type())
+}
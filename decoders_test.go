@@ -0,0 +1,199 @@
+package caddycfg
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisteredDecoders(t *testing.T) {
+	type config struct {
+		Pattern  *regexp.Regexp `json:"pattern"`
+		URL      url.URL        `json:"url"`
+		URLPtr   *url.URL       `json:"url_ptr"`
+		Nets     []net.IPNet    `json:"nets"`
+		Location *time.Location `json:"location"`
+	}
+
+	_, ipNet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	c := caddy.NewTestController("http", `root {
+        pattern ^/api/(v[0-9]+)$
+        url https://example.com/path?a=b
+        url_ptr http://localhost:8080
+        nets 10.0.0.0/8 ::1/128
+        location UTC
+    }`)
+	d := NewDecoder(c)
+	d.RegisterDecoder(reflect.TypeOf((*time.Location)(nil)), func(t Token) (interface{}, error) {
+		return time.LoadLocation(t.Value)
+	})
+	var cfg config
+	require.NoError(t, d.Decode(&cfg))
+
+	require.Equal(t, `^/api/(v[0-9]+)$`, cfg.Pattern.String())
+	require.Equal(t, "https://example.com/path?a=b", cfg.URL.String())
+	require.Equal(t, "localhost:8080", cfg.URLPtr.Host)
+	require.Len(t, cfg.Nets, 2)
+	require.Equal(t, *ipNet, cfg.Nets[0])
+	require.Equal(t, "::1/128", cfg.Nets[1].String())
+	require.Equal(t, time.UTC, cfg.Location)
+}
+
+func TestRegisteredDecodersErrors(t *testing.T) {
+	type config struct {
+		Pattern *regexp.Regexp `json:"pattern"`
+		Net     net.IPNet      `json:"net"`
+		Value   int            `json:"value"`
+	}
+	type sample struct {
+		name     string
+		input    string
+		register func(d *Decoder)
+		errMsg   string
+		kind     ErrorKind
+	}
+
+	samples := []sample{
+		{
+			name:   "invalid-regexp",
+			input:  "root {\n  pattern a(b\n}",
+			errMsg: "Testfile:2: cannot unmarshal into *regexp.Regexp: error parsing regexp: missing closing ): `a(b`",
+			kind:   KindInvalidValue,
+		},
+		{
+			name:   "invalid-cidr",
+			input:  "root {\n  net 10.0.0.1\n}",
+			errMsg: "Testfile:2: cannot unmarshal into net.IPNet: invalid CIDR address: 10.0.0.1",
+			kind:   KindInvalidValue,
+		},
+		{
+			name:  "decoder-precedence",
+			input: "root {\n  value 0x10\n}",
+			register: func(d *Decoder) {
+				d.RegisterDecoder(reflect.TypeOf(0), func(t Token) (interface{}, error) {
+					return nil, TokenErrorf(t, "custom decoder used")
+				})
+			},
+			errMsg: "Testfile:2: custom decoder used",
+			kind:   KindCustom,
+		},
+		{
+			name:  "decoder-wrong-type",
+			input: "root {\n  value 1\n}",
+			register: func(d *Decoder) {
+				d.RegisterDecoder(reflect.TypeOf(0), func(t Token) (interface{}, error) {
+					return t.Value, nil
+				})
+			},
+			errMsg: "Testfile:2: decoder of int returned string",
+			kind:   KindUnsupportedType,
+		},
+		{
+			name:   "no-data",
+			input:  "root {\n  pattern\n}",
+			errMsg: "Testfile:1: got no data for *regexp.Regexp",
+			kind:   KindNoData,
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			d := NewDecoder(caddy.NewTestController("http", s.input))
+			if s.register != nil {
+				s.register(d)
+			}
+			var cfg config
+			err := d.Decode(&cfg)
+			require.EqualError(t, err, s.errMsg)
+			var te *TokenError
+			require.True(t, errors.As(err, &te))
+			require.Equal(t, s.kind, te.Kind)
+		})
+	}
+}
+
+func TestURLScheme(t *testing.T) {
+	type config struct {
+		Upstream  *url.URL  `caddy:"upstream,scheme=http|https"`
+		Upstreams []url.URL `caddy:"upstreams,scheme=ws|wss"`
+		Callback  url.URL   `caddy:"callback,scheme,omitempty"`
+	}
+	type sample struct {
+		name   string
+		input  string
+		errMsg string
+	}
+
+	samples := []sample{
+		{
+			name:  "success",
+			input: "root {\n  upstream HTTPS://example.com\n  upstreams ws://a wss://b\n  callback mailto:admin@example.com\n}",
+		},
+		{
+			name:   "error-scheme",
+			input:  "root {\n  upstream ftp://example.com\n}",
+			errMsg: "Caddyfile:2: cannot unmarshal into url.URL: scheme of URL ftp://example.com must be http or https",
+		},
+		{
+			name:   "error-scheme-in-slice",
+			input:  "root {\n  upstreams ws://a http://b\n}",
+			errMsg: "Caddyfile:2: cannot unmarshal into url.URL: scheme of URL http://b must be ws or wss",
+		},
+		{
+			name:   "error-no-scheme",
+			input:  "root {\n  callback /path\n}",
+			errMsg: "Caddyfile:2: cannot unmarshal into url.URL: URL /path has no scheme",
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			var cfg config
+			err := UnmarshalString(s.input, &cfg)
+			if len(s.errMsg) > 0 {
+				require.EqualError(t, err, s.errMsg)
+				require.ErrorIs(t, err, ErrInvalidValue)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	type config struct {
+		Root  Path   `caddy:"root,default=www"`
+		Cert  Path   `caddy:"cert"`
+		Logs  *Path  `caddy:"logs"`
+		Extra []Path `caddy:"extra"`
+	}
+
+	var cfg config
+	err := UnmarshalReader("/etc/caddy/Caddyfile", strings.NewReader(`root {
+    cert ../certs/cert.pem
+    logs /var/log/caddy
+    extra a ./b/../c
+}`), &cfg)
+	require.NoError(t, err)
+
+	logs := Path("/var/log/caddy")
+	require.Equal(t, config{
+		Root:  "/etc/caddy/www",
+		Cert:  "/etc/certs/cert.pem",
+		Logs:  &logs,
+		Extra: []Path{"/etc/caddy/a", "/etc/caddy/c"},
+	}, cfg)
+
+	err = UnmarshalString("root {\n  cert \"\"\n}", &cfg)
+	require.EqualError(t, err, "Caddyfile:2: cannot unmarshal into caddycfg.Path: empty path")
+}
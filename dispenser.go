@@ -0,0 +1,135 @@
+package caddycfg
+
+// Dispenser mirrors methods of Caddy v2 *caddyfile.Dispenser used for decoding, so that it can be decoded
+// without depending on Caddy v2
+type Dispenser interface {
+	Next() bool
+	NextArg() bool
+	NextBlock(initialNestingLevel int) bool
+	Val() string
+	File() string
+	Line() int
+	Nesting() int
+}
+
+// dispenserStream stream over an entry of the dispenser: the head line and a block it opens. The rest of dispenser
+// tokens are never consumed, so that the caller can go on with its Next or NextBlock loop. Nesting of the dispenser
+// is left intact, as blocks of the entry are consumed in full
+type dispenserStream struct {
+	src       Dispenser
+	columns   *columnResolver
+	cur       Token
+	started   bool
+	depth     int
+	finished  bool
+	confirmed bool
+}
+
+// newDispenserStream returns stream over d. The head token is the current one of the dispenser if it has any, e.g.
+// when its name was consumed already or it is a key inside the NextBlock loop, and the next token otherwise. Columns
+// of tokens are only resolved if columns is not nil
+func newDispenserStream(d Dispenser, columns *columnResolver) Stream {
+	return &dispenserStream{
+		src:       d,
+		columns:   columns,
+		confirmed: true,
+	}
+}
+
+// Next checks if next token is available
+func (s *dispenserStream) Next() bool {
+	if !s.confirmed {
+		return true
+	}
+	if s.finished {
+		return false
+	}
+	if !s.started {
+		return s.start()
+	}
+	// the entry outside of its block only continues on the current line
+	var ok bool
+	if s.depth > 0 {
+		ok = s.src.Next()
+	} else {
+		ok = s.src.NextArg()
+	}
+	if !ok {
+		s.finished = true
+		return false
+	}
+	s.read()
+	return true
+}
+
+// NextArg checks if next token at current row is available
+func (s *dispenserStream) NextArg() bool {
+	if !s.confirmed {
+		return true
+	}
+	if s.finished {
+		return false
+	}
+	if !s.started {
+		return s.start()
+	}
+	if !s.src.NextArg() {
+		return false
+	}
+	s.read()
+	return true
+}
+
+// start takes the head token
+func (s *dispenserStream) start() bool {
+	s.started = true
+	if s.src.Val() == "" && !s.src.Next() {
+		s.finished = true
+		return false
+	}
+	s.read()
+	return true
+}
+
+// read takes current token of the dispenser
+func (s *dispenserStream) read() {
+	s.cur = Token{
+		File:  s.src.File(),
+		Value: s.src.Val(),
+		Lin:   s.src.Line(),
+	}
+	if s.columns != nil {
+		s.columns.resolve(&s.cur)
+	}
+	s.confirmed = false
+
+	s.depth += blockDepthChange(s.cur)
+}
+
+// Token ...
+func (s *dispenserStream) Token() Token {
+	return s.cur
+}
+
+// Confirm ...
+func (s *dispenserStream) Confirm() {
+	s.confirmed = true
+}
+
+// NewDispenserDecoder creates a decoder of the dispenser entry, see UnmarshalDispenser
+func NewDispenserDecoder(d Dispenser, opts ...Option) *Decoder {
+	dec := NewStreamDecoder(nil, opts...)
+	var columns *columnResolver
+	if dec.opts.resolveColumns {
+		columns = newColumnResolver(nil)
+	}
+	dec.stream = newDispenserStream(d, columns)
+	return dec
+}
+
+// UnmarshalDispenser unmarshal an entry of Caddy v2 dispenser into dest. The entry head is the current token of the
+// dispenser if it has one and the next token otherwise, so it works both in UnmarshalCaddyfile methods and for keys
+// inside NextBlock loops. Only the head line and a block it opens are consumed
+func UnmarshalDispenser(d Dispenser, dest interface{}) error {
+	return NewDispenserDecoder(d).Decode(dest)
+}
@@ -0,0 +1,190 @@
+package caddycfg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDispenser follows Caddy v2 caddyfile.Dispenser semantics
+type fakeDispenser struct {
+	tokens  []Token
+	cursor  int
+	nesting int
+}
+
+func newFakeDispenser(t *testing.T, src string) *fakeDispenser {
+	tokens, err := tokenize("Caddyfile", []byte(src))
+	require.NoError(t, err)
+	return &fakeDispenser{
+		tokens: tokens,
+		cursor: -1,
+	}
+}
+
+func (d *fakeDispenser) Next() bool {
+	if d.cursor < len(d.tokens)-1 {
+		d.cursor++
+		return true
+	}
+	return false
+}
+
+func (d *fakeDispenser) NextArg() bool {
+	if !d.nextOnSameLine() {
+		return false
+	}
+	d.cursor++
+	return true
+}
+
+func (d *fakeDispenser) NextBlock(initialNestingLevel int) bool {
+	if d.nesting > initialNestingLevel {
+		if !d.Next() {
+			return false
+		}
+		if d.Val() == "}" && !d.nextOnSameLine() {
+			d.nesting--
+		} else if d.Val() == "{" && !d.nextOnSameLine() {
+			d.nesting++
+		}
+		return d.nesting > initialNestingLevel
+	}
+	if !d.nextOnSameLine() || !d.Next() {
+		return false
+	}
+	if d.Val() != "{" {
+		d.cursor--
+		return false
+	}
+	d.Next()
+	if d.Val() == "}" {
+		return false
+	}
+	d.nesting++
+	return true
+}
+
+func (d *fakeDispenser) nextOnSameLine() bool {
+	if d.cursor < 0 {
+		return true
+	}
+	if d.cursor >= len(d.tokens)-1 {
+		return false
+	}
+	cur := d.tokens[d.cursor]
+	next := d.tokens[d.cursor+1]
+	return cur.File == next.File && cur.Lin+strings.Count(cur.Value, "\n") == next.Lin
+}
+
+func (d *fakeDispenser) token() Token {
+	if d.cursor < 0 || d.cursor >= len(d.tokens) {
+		return Token{}
+	}
+	return d.tokens[d.cursor]
+}
+
+func (d *fakeDispenser) Val() string  { return d.token().Value }
+func (d *fakeDispenser) File() string { return d.token().File }
+func (d *fakeDispenser) Line() int    { return d.token().Lin }
+func (d *fakeDispenser) Nesting() int { return d.nesting }
+
+func TestUnmarshalDispenser(t *testing.T) {
+	type (
+		sub struct {
+			A int `json:"a"`
+		}
+		config struct {
+			Args
+			Sub  sub   `json:"sub"`
+			List []int `json:"list"`
+		}
+	)
+	src := `plugin x y {
+    sub {
+        a 1
+    }
+    list 1 2
+}
+other z`
+	expected := config{Args: Args{data: []string{"x", "y"}}, Sub: sub{A: 1}, List: []int{1, 2}}
+
+	t.Run("segment-start", func(t *testing.T) {
+		d := newFakeDispenser(t, src)
+		var cfg config
+		require.NoError(t, UnmarshalDispenser(d, &cfg))
+		require.Equal(t, expected, cfg)
+
+		// the next directive is left intact
+		require.Equal(t, "}", d.Val())
+		require.True(t, d.Next())
+		require.Equal(t, "other", d.Val())
+	})
+
+	t.Run("head-consumed", func(t *testing.T) {
+		d := newFakeDispenser(t, src)
+		require.True(t, d.Next())
+		var cfg config
+		decoder := NewDispenserDecoder(d)
+		require.NoError(t, decoder.Decode(&cfg))
+		require.Equal(t, expected, cfg)
+		require.Equal(t, Token{File: "Caddyfile", Value: "plugin", Lin: 1}, decoder.HeadToken())
+	})
+
+	t.Run("next-block-loop", func(t *testing.T) {
+		d := newFakeDispenser(t, src)
+		require.True(t, d.Next())
+		require.True(t, d.NextArg())
+		require.True(t, d.NextArg())
+
+		var cfg config
+		var keys []string
+		for d.NextBlock(0) {
+			keys = append(keys, d.Val())
+			switch d.Val() {
+			case "sub":
+				require.NoError(t, UnmarshalDispenser(d, &cfg.Sub))
+			case "list":
+				require.NoError(t, UnmarshalDispenser(d, &cfg.List))
+			}
+		}
+		require.Equal(t, []string{"sub", "list"}, keys)
+		require.Equal(t, config{Sub: sub{A: 1}, List: []int{1, 2}}, cfg)
+		require.Equal(t, 0, d.Nesting())
+		require.True(t, d.Next())
+		require.Equal(t, "other", d.Val())
+	})
+
+	t.Run("error-trailing-data", func(t *testing.T) {
+		d := newFakeDispenser(t, "plugin {\n} extra\n")
+		var cfg config
+		require.EqualError(t, UnmarshalDispenser(d, &cfg), "Caddyfile:2: got unexpected data 'extra' for plugin 'plugin'")
+	})
+
+	t.Run("error-no-data", func(t *testing.T) {
+		d := newFakeDispenser(t, "")
+		var cfg config
+		require.ErrorIs(t, UnmarshalDispenser(d, &cfg), ErrNoData)
+	})
+}
+
+func TestDispenserColumns(t *testing.T) {
+	src := "plugin x\n"
+	name := filepath.Join(t.TempDir(), "Caddyfile")
+	require.NoError(t, os.WriteFile(name, []byte(src), 0o644))
+	tokens, err := tokenize(name, []byte(src))
+	require.NoError(t, err)
+
+	// columns are not resolved by default
+	var cfg []string
+	decoder := NewDispenserDecoder(&fakeDispenser{tokens: tokens, cursor: -1})
+	require.NoError(t, decoder.Decode(&cfg))
+	require.Equal(t, Token{File: name, Value: "plugin", Lin: 1}, decoder.HeadToken())
+
+	decoder = NewDispenserDecoder(&fakeDispenser{tokens: tokens, cursor: -1}, ResolveColumns(true))
+	require.NoError(t, decoder.Decode(&cfg))
+	require.Equal(t, Token{File: name, Value: "plugin", Lin: 1, Col: 1, EndCol: 7}, decoder.HeadToken())
+}
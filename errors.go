@@ -0,0 +1,177 @@
+package caddycfg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Sentinel errors for kinds of decoding errors, TokenError of the kind matches the corresponding one with errors.Is
+var (
+	ErrInvalidValue    = errors.New("invalid value")
+	ErrNoData          = errors.New("no data")
+	ErrUnknownKey      = errors.New("unknown key")
+	ErrDuplicateKey    = errors.New("duplicate key")
+	ErrMissingKey      = errors.New("missing required key")
+	ErrBlockExpected   = errors.New("block expected")
+	ErrUnexpectedBlock = errors.New("unexpected block")
+	ErrUnclosedBlock   = errors.New("unclosed block")
+	ErrUnexpectedData  = errors.New("unexpected data")
+	ErrUnsupportedType = errors.New("unsupported type")
+)
+
+// ErrorKind kind of a decoding error
+type ErrorKind int
+
+// Decoding error kinds
+const (
+	// KindCustom errors created with NewTokenError and TokenErrorf, e.g. by validators
+	KindCustom ErrorKind = iota
+	KindInvalidValue
+	KindNoData
+	KindUnknownKey
+	KindDuplicateKey
+	KindMissingKey
+	KindBlockExpected
+	KindUnexpectedBlock
+	KindUnclosedBlock
+	KindUnexpectedData
+	KindUnsupportedType
+)
+
+var kindErrors = map[ErrorKind]error{
+	KindInvalidValue:    ErrInvalidValue,
+	KindNoData:          ErrNoData,
+	KindUnknownKey:      ErrUnknownKey,
+	KindDuplicateKey:    ErrDuplicateKey,
+	KindMissingKey:      ErrMissingKey,
+	KindBlockExpected:   ErrBlockExpected,
+	KindUnexpectedBlock: ErrUnexpectedBlock,
+	KindUnclosedBlock:   ErrUnclosedBlock,
+	KindUnexpectedData:  ErrUnexpectedData,
+	KindUnsupportedType: ErrUnsupportedType,
+}
+
+// String ...
+func (k ErrorKind) String() string {
+	if err, ok := kindErrors[k]; ok {
+		return err.Error()
+	}
+	return "custom"
+}
+
+// TokenError error caused with the given token
+type TokenError struct {
+	Token
+
+	// Path Go path of the value being decoded relative to the destination, e.g. Key1.Sub[2]
+	Path string
+	Kind ErrorKind
+	Err  error
+}
+
+// Error ...
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Lin, e.Err)
+}
+
+// Unwrap ...
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a sentinel error of the error kind
+func (e *TokenError) Is(target error) bool {
+	err, ok := kindErrors[e.Kind]
+	return ok && err == target
+}
+
+// NewTokenError diagnose a error caused with a given token
+func NewTokenError(t Token, err error) error {
+	return &TokenError{
+		Token: t,
+		Err:   err,
+	}
+}
+
+// TokenErrorf diagnose a error caused with a given token with custom error message
+func TokenErrorf(t Token, format string, a ...interface{}) error {
+	return NewTokenError(t, fmt.Errorf(format, a...))
+}
+
+// tokenErrorf creates an error of the given kind
+func tokenErrorf(t Token, kind ErrorKind, format string, a ...interface{}) error {
+	return &TokenError{
+		Token: t,
+		Kind:  kind,
+		Err:   fmt.Errorf(format, a...),
+	}
+}
+
+// MultiError errors collected while decoding with CollectErrors option set, in source order
+type MultiError []error
+
+// Error ...
+func (e MultiError) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns collected errors
+func (e MultiError) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of collected errors matches target
+func (e MultiError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of collected errors that matches target
+func (e MultiError) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortErrors sorts errors in source order: by file in order of their appearance, then by line and column
+func sortErrors(errs []error) {
+	files := map[string]int{}
+	for _, err := range errs {
+		if te, ok := err.(*TokenError); ok {
+			if _, ok := files[te.File]; !ok {
+				files[te.File] = len(files)
+			}
+		}
+	}
+	position := func(err error) (int, int, int) {
+		te, ok := err.(*TokenError)
+		if !ok {
+			return len(files), 0, 0
+		}
+		return files[te.File], te.Lin, te.Col
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		fileI, linI, colI := position(errs[i])
+		fileJ, linJ, colJ := position(errs[j])
+		if fileI != fileJ {
+			return fileI < fileJ
+		}
+		if linI != linJ {
+			return linI < linJ
+		}
+		return colI < colJ
+	})
+}
@@ -0,0 +1,229 @@
+package caddycfg
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/caddyserver/caddy"
+	"github.com/stretchr/testify/require"
+)
+
+type failingValidator struct {
+	A int `caddy:"a"`
+}
+
+func (v failingValidator) Err(head Token) error {
+	if v.A < 0 {
+		return errors.New("a must not be negative")
+	}
+	return nil
+}
+
+func TestCollectErrors(t *testing.T) {
+	type config struct {
+		A     int               `caddy:"a"`
+		B     []int             `caddy:"b"`
+		C     map[string]int    `caddy:"c"`
+		D     []bool            `caddy:"d"`
+		Valid *failingValidator `caddy:"valid"`
+		E     string            `caddy:"e,required"`
+	}
+
+	c := caddy.NewTestController("http", `root {
+    a x
+    unknown 1 {
+        a 1
+    }
+    b 1 2 y
+    c {
+        k1 1
+        k2 z
+        k3 3
+    }
+    d {
+        true
+        maybe
+    }
+    valid {
+        a -1
+    }
+}`)
+	var dest config
+	err := NewDecoder(c, CollectErrors(true)).Decode(&dest)
+	require.Error(t, err)
+
+	var merr MultiError
+	require.True(t, errors.As(err, &merr))
+	var lines []int
+	for _, e := range merr {
+		var te *TokenError
+		require.True(t, errors.As(e, &te))
+		lines = append(lines, te.Lin)
+	}
+	require.Equal(t, []int{1, 2, 3, 6, 9, 14, 16}, lines)
+	require.Equal(t, map[string]int{"k1": 1, "k3": 3}, dest.C)
+	require.Equal(t, []bool{true}, dest.D)
+
+	var te *TokenError
+	require.True(t, errors.As(err, &te))
+	require.Equal(t, 1, te.Lin)
+}
+
+func TestCollectErrorsUnclosedBlock(t *testing.T) {
+	type config struct {
+		A int `caddy:"a"`
+		B struct {
+			C int `caddy:"c"`
+		} `caddy:"b"`
+	}
+
+	c := caddy.NewTestController("http", `root {
+    a x
+    b {
+        c y
+`)
+	var dest config
+	err := NewDecoder(c, CollectErrors(true)).Decode(&dest)
+	require.Error(t, err)
+	require.Len(t, err.(MultiError), 3)
+}
+
+func TestCollectErrorsSuccess(t *testing.T) {
+	c := caddy.NewTestController("http", `root {
+    a 1
+}`)
+	var dest failingValidator
+	require.NoError(t, NewDecoder(c, CollectErrors(true)).Decode(&dest))
+	require.Equal(t, failingValidator{A: 1}, dest)
+}
+
+func TestTokenError(t *testing.T) {
+	type (
+		sub struct {
+			Values []int `caddy:"values"`
+		}
+		config struct {
+			Key1 struct {
+				Sub []sub `caddy:"sub"`
+			} `caddy:"key1"`
+			Map map[string]int `caddy:"map"`
+		}
+		sample struct {
+			name     string
+			input    string
+			sentinel error
+			kind     ErrorKind
+			path     string
+			line     int
+		}
+	)
+
+	samples := []sample{
+		{
+			name: "invalid-value",
+			input: `root {
+                        key1 {
+                            sub {
+                                {
+                                    values 1
+                                }
+                                {
+                                    values 1 2 x
+                                }
+                            }
+                        }
+                    }`,
+			sentinel: ErrInvalidValue,
+			kind:     KindInvalidValue,
+			path:     "Key1.Sub[1].Values[2]",
+			line:     8,
+		},
+		{
+			name: "invalid-map-value",
+			input: `root {
+                        map {
+                            a 1
+                            b x
+                        }
+                    }`,
+			sentinel: ErrInvalidValue,
+			kind:     KindInvalidValue,
+			path:     `Map["b"]`,
+			line:     4,
+		},
+		{
+			name: "unknown-key",
+			input: `root {
+                        key1 {
+                            key2 1
+                        }
+                    }`,
+			sentinel: ErrUnknownKey,
+			kind:     KindUnknownKey,
+			path:     "Key1",
+			line:     3,
+		},
+		{
+			name: "no-data",
+			input: `root {
+                        map
+                    }`,
+			sentinel: ErrBlockExpected,
+			kind:     KindBlockExpected,
+			path:     "Map",
+			line:     1,
+		},
+		{
+			name: "unclosed-block",
+			input: `root {
+                        map {
+                            a 1`,
+			sentinel: ErrUnclosedBlock,
+			kind:     KindUnclosedBlock,
+			path:     "Map",
+			line:     3,
+		},
+		{
+			name: "unexpected-block",
+			input: `root {
+                        key1 {
+                            sub {
+                                {
+                                    values 1 {
+                                    }
+                                }
+                            }
+                        }
+                    }`,
+			sentinel: ErrUnexpectedBlock,
+			kind:     KindUnexpectedBlock,
+			path:     "Key1.Sub[0].Values",
+			line:     5,
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			c := caddy.NewTestController("http", s.input)
+			var dest config
+			err := Unmarshal(c, &dest)
+			require.Error(t, err)
+			require.True(t, errors.Is(err, s.sentinel))
+
+			var te *TokenError
+			require.True(t, errors.As(err, &te))
+			require.Equal(t, s.kind, te.Kind)
+			require.Equal(t, s.path, te.Path)
+			require.Equal(t, s.line, te.Lin)
+		})
+	}
+}
+
+func TestCustomTokenError(t *testing.T) {
+	cause := errors.New("cause")
+	err := NewTokenError(Token{File: "Caddyfile", Lin: 12}, cause)
+	require.EqualError(t, err, "Caddyfile:12: cause")
+	require.True(t, errors.Is(err, cause))
+	require.False(t, errors.Is(err, ErrInvalidValue))
+	require.Equal(t, KindCustom, err.(*TokenError).Kind)
+}
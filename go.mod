@@ -1,9 +1,17 @@
 module github.com/sirkon/caddycfg
 
-go 1.14
+go 1.18
 
 require (
 	github.com/caddyserver/caddy v1.0.5
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.7.0
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/flynn/go-shlex v0.0.0-20150515145356-3f9db97f8568 // indirect
+	github.com/google/uuid v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
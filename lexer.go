@@ -0,0 +1,182 @@
+package caddycfg
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenize splits Caddyfile source into tokens just like Caddy lexer does:
+//   - tokens are separated by whitespace
+//   - "quoted" tokens may contain whitespace and newlines, only quotes can be escaped in them with \
+//   - `backticked` tokens are taken as is, with no escaping at all
+//   - # starts a comment if it is at the start of a token
+//   - \ at the end of a line joins the next line to the current one, so tokens there are on the same line
+//
+// Col and EndCol of tokens are set too. They are rune based and cover the quotes of quoted tokens
+func tokenize(file string, src []byte) ([]Token, error) {
+	var (
+		tokens []Token
+		cur    Token
+		val    []rune
+
+		inToken  bool
+		quoted   bool
+		btQuoted bool
+		escaped  bool
+		comment  bool
+
+		line        = 1
+		skipped     int
+		col         = 1
+		escapeCol   int
+		escapeLine  int
+		escapeInTok bool
+	)
+
+	makeToken := func(endCol int) {
+		cur.Value = string(val)
+		cur.EndCol = endCol
+		tokens = append(tokens, cur)
+		val = val[:0]
+		inToken = false
+	}
+	newLine := func() {
+		line += 1 + skipped
+		skipped = 0
+	}
+
+	for _, ch := range strings.TrimPrefix(string(src), "\ufeff") {
+		chCol := col
+		if ch == '\n' {
+			col = 1
+		} else {
+			col++
+		}
+
+		if comment {
+			if ch == '\n' {
+				newLine()
+				comment = false
+			}
+			continue
+		}
+
+		if quoted {
+			switch {
+			case escaped:
+				// only quotes can be escaped in quoted tokens
+				if ch != '"' {
+					val = append(val, '\\')
+				}
+				escaped = false
+			case ch == '\\':
+				escaped = true
+				continue
+			case ch == '"':
+				quoted = false
+				makeToken(chCol + 1)
+				continue
+			}
+			if ch == '\n' {
+				newLine()
+			}
+			val = append(val, ch)
+			continue
+		}
+
+		if btQuoted {
+			if ch == '`' {
+				btQuoted = false
+				makeToken(chCol + 1)
+				continue
+			}
+			if ch == '\n' {
+				newLine()
+			}
+			val = append(val, ch)
+			continue
+		}
+
+		if ch == '\\' && !escaped {
+			escaped = true
+			escapeCol = chCol
+			escapeLine = line
+			escapeInTok = inToken
+			continue
+		}
+
+		if unicode.IsSpace(ch) {
+			if ch == '\n' {
+				// escaped newlines chain arguments onto multiple lines
+				if escaped {
+					skipped++
+					escaped = false
+				} else {
+					newLine()
+				}
+			}
+			if inToken {
+				makeToken(chCol)
+			}
+			continue
+		}
+
+		if ch == '#' && !inToken {
+			comment = true
+			escaped = false
+			continue
+		}
+
+		if !inToken {
+			inToken = true
+			cur = Token{
+				File: file,
+				Lin:  line,
+				Col:  chCol,
+			}
+			if escaped && !escapeInTok {
+				cur.Lin = escapeLine
+				cur.Col = escapeCol
+			}
+			if !escaped {
+				switch ch {
+				case '"':
+					quoted = true
+					continue
+				case '`':
+					btQuoted = true
+					continue
+				}
+			}
+		}
+
+		if escaped {
+			// the backslash is kept in unquoted tokens, except for escaped quotes at the token start
+			if !(len(val) == 0 && (ch == '"' || ch == '`')) {
+				val = append(val, '\\')
+			}
+			escaped = false
+		}
+		val = append(val, ch)
+	}
+
+	if quoted || btQuoted {
+		return nil, tokenErrorf(cur, KindUnexpectedData, "unterminated quoted token")
+	}
+	if escaped && !inToken {
+		inToken = true
+		cur = Token{
+			File: file,
+			Lin:  escapeLine,
+			Col:  escapeCol,
+		}
+	}
+	if escaped {
+		val = append(val, '\\')
+	}
+	if inToken {
+		makeToken(col)
+	}
+
+	return tokens, nil
+}
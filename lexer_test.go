@@ -0,0 +1,122 @@
+package caddycfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	type sample struct {
+		name     string
+		input    string
+		expected []Token
+		wantErr  bool
+	}
+
+	tok := func(value string, lin, col, endCol int) Token {
+		return Token{File: "Caddyfile", Value: value, Lin: lin, Col: col, EndCol: endCol}
+	}
+
+	samples := []sample{
+		{
+			name:  "plain",
+			input: "root a  b\n\tkey {\n}",
+			expected: []Token{
+				tok("root", 1, 1, 5),
+				tok("a", 1, 6, 7),
+				tok("b", 1, 9, 10),
+				tok("key", 2, 2, 5),
+				tok("{", 2, 6, 7),
+				tok("}", 3, 1, 2),
+			},
+		},
+		{
+			name:  "quoted",
+			input: `root "a \"b\" \c" ""`,
+			expected: []Token{
+				tok("root", 1, 1, 5),
+				tok(`a "b" \c`, 1, 6, 18),
+				tok("", 1, 19, 21),
+			},
+		},
+		{
+			name:  "quoted-multiline",
+			input: "root \"a\nb\" c\nd",
+			expected: []Token{
+				tok("root", 1, 1, 5),
+				tok("a\nb", 1, 6, 3),
+				tok("c", 2, 4, 5),
+				tok("d", 3, 1, 2),
+			},
+		},
+		{
+			name:  "backticks",
+			input: "root `a \"b\" \\` c",
+			expected: []Token{
+				tok("root", 1, 1, 5),
+				tok(`a "b" \`, 1, 6, 15),
+				tok("c", 1, 16, 17),
+			},
+		},
+		{
+			name:  "comments",
+			input: "# comment\nroot a#b # comment\nc",
+			expected: []Token{
+				tok("root", 2, 1, 5),
+				tok("a#b", 2, 6, 9),
+				tok("c", 3, 1, 2),
+			},
+		},
+		{
+			name:  "line-continuation",
+			input: "root a \\\n  b\nc",
+			expected: []Token{
+				tok("root", 1, 1, 5),
+				tok("a", 1, 6, 7),
+				tok("b", 1, 3, 4),
+				tok("c", 3, 1, 2),
+			},
+		},
+		{
+			name:  "escapes-unquoted",
+			input: `root a\b \"c`,
+			expected: []Token{
+				tok("root", 1, 1, 5),
+				tok(`a\b`, 1, 6, 9),
+				tok(`"c`, 1, 10, 13),
+			},
+		},
+		{
+			name:  "unicode-columns",
+			input: "root ключ значение\r\n",
+			expected: []Token{
+				tok("root", 1, 1, 5),
+				tok("ключ", 1, 6, 10),
+				tok("значение", 1, 11, 19),
+			},
+		},
+		{
+			name:    "error-unterminated-quote",
+			input:   `root "a b`,
+			wantErr: true,
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			tokens, err := tokenize("Caddyfile", []byte(s.input))
+			if err != nil {
+				if !s.wantErr {
+					t.Error(err)
+				}
+				return
+			}
+			if err == nil && s.wantErr {
+				t.Errorf("error expected")
+				return
+			}
+			require.Equal(t, s.expected, tokens)
+		})
+	}
+}
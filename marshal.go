@@ -0,0 +1,469 @@
+package caddycfg
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Marshal encodes v into Caddyfile directive text with the given head (plugin name). It is an inverse of Unmarshal,
+// i.e. the text it produces unmarshals back into the value of the same type
+func Marshal(head string, v interface{}) ([]byte, error) {
+	value := reflect.ValueOf(v)
+	if !value.IsValid() {
+		return nil, fmt.Errorf("marshal of nil value")
+	}
+	m := &caddyCfgMarshaler{lineStart: true}
+	if err := m.entry(head, value); err != nil {
+		return nil, err
+	}
+	return m.buf.Bytes(), nil
+}
+
+type caddyCfgMarshaler struct {
+	buf       bytes.Buffer
+	indent    int
+	lineStart bool
+
+	// heads is set while a value of a field tagged with heads option is written, its items are written with heads
+	heads bool
+}
+
+// token writes a single token, it is prepended with either an indentation or a space depending on a position in a line
+func (m *caddyCfgMarshaler) token(value string) {
+	if m.lineStart {
+		m.buf.WriteString(strings.Repeat("\t", m.indent))
+		m.lineStart = false
+	} else {
+		m.buf.WriteByte(' ')
+	}
+	m.buf.WriteString(value)
+}
+
+// text writes a token whose value may need to be quoted
+func (m *caddyCfgMarshaler) text(value string) error {
+	quoted, err := quoteToken(value)
+	if err != nil {
+		return err
+	}
+	m.token(quoted)
+	return nil
+}
+
+func (m *caddyCfgMarshaler) newline() {
+	m.buf.WriteByte('\n')
+	m.lineStart = true
+}
+
+func (m *caddyCfgMarshaler) openBlock() {
+	m.token("{")
+	m.newline()
+	m.indent++
+}
+
+func (m *caddyCfgMarshaler) closeBlock() {
+	m.indent--
+	m.token("}")
+}
+
+// entry writes a line starting with the head and followed with a representation of v
+func (m *caddyCfgMarshaler) entry(head string, v reflect.Value) error {
+	if err := m.text(head); err != nil {
+		return err
+	}
+	if err := m.value(v); err != nil {
+		return fmt.Errorf("marshal %s: %s", head, err)
+	}
+	m.newline()
+	return nil
+}
+
+func (m *caddyCfgMarshaler) value(v reflect.Value) error {
+	if isRegisteredType(v.Type()) {
+		return m.registered(v)
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.Kind() == reflect.Interface && v.IsNil() {
+			// nil values of a generic tree are entries with no data
+			return nil
+		}
+		if v.IsNil() {
+			return fmt.Errorf("marshal of nil %s", v.Type())
+		}
+		if v.Kind() == reflect.Ptr && (v.Type().Implements(textMarshalerType) || v.Type().Implements(jsonMarshalerType)) {
+			return m.marshaler(v)
+		}
+		v = v.Elem()
+	}
+
+	// make value addressable to reach methods with pointer receivers
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	if ptr.Type().Implements(textMarshalerType) || ptr.Type().Implements(jsonMarshalerType) {
+		return m.marshaler(ptr)
+	}
+
+	if v.Kind() != reflect.Struct {
+		switch args := ptr.Interface().(type) {
+		case ArgumentsConsumer:
+			return m.arguments(args.Arguments())
+		case ArgumentsCollector:
+			return m.arguments(args.Arguments())
+		}
+	}
+
+	if v.Type() == durationType {
+		m.token(time.Duration(v.Int()).String())
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		m.token(strconv.FormatBool(v.Bool()))
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		m.token(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		m.token(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32:
+		m.token(strconv.FormatFloat(v.Float(), 'g', -1, 32))
+	case reflect.Float64:
+		m.token(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.String:
+		return m.text(v.String())
+	case reflect.Slice, reflect.Array:
+		return m.slice(v)
+	case reflect.Map:
+		return m.mapping(v)
+	case reflect.Struct:
+		return m.structure(ptr)
+	default:
+		return fmt.Errorf("marshal of %s is not supported", v.Type())
+	}
+	return nil
+}
+
+// marshaler writes a value of the type implementing either encoding.TextMarshaler or json.Marshaler, the first one
+// takes precedence just like encoding.TextUnmarshaler does for unmarshaling
+func (m *caddyCfgMarshaler) marshaler(v reflect.Value) error {
+	var data []byte
+	var err error
+	switch marshaler := v.Interface().(type) {
+	case encoding.TextMarshaler:
+		data, err = marshaler.MarshalText()
+	case json.Marshaler:
+		data, err = marshaler.MarshalJSON()
+	}
+	if err != nil {
+		return err
+	}
+	return m.text(string(data))
+}
+
+// registered writes a value of the type having a registered decoder
+func (m *caddyCfgMarshaler) registered(v reflect.Value) error {
+	for v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return fmt.Errorf("marshal of nil %s", v.Type())
+	}
+	if v.Kind() != reflect.Ptr {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	value, err := registeredString(v)
+	if err != nil {
+		return err
+	}
+	return m.text(value)
+}
+
+func (m *caddyCfgMarshaler) arguments(args []string) error {
+	for _, arg := range args {
+		if err := m.text(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// slice writes slices of primitive types inline and uses block representation with an item per line for the rest
+func (m *caddyCfgMarshaler) slice(v reflect.Value) error {
+	if v.Type().Elem().Kind() == reflect.Interface {
+		return m.genericSlice(v)
+	}
+	if isPrimitiveType(v.Type().Elem()) {
+		for i := 0; i < v.Len(); i++ {
+			if err := m.value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if v.Len() == 0 {
+		return nil
+	}
+	m.openBlock()
+	headed := isHeadedType(v.Type().Elem(), defaultTagName, m.heads)
+	prevHeads := m.heads
+	m.heads = false
+	defer func() {
+		m.heads = prevHeads
+	}()
+	for i := 0; i < v.Len(); i++ {
+		if headed {
+			if err := m.itemHead(v.Index(i)); err != nil {
+				return fmt.Errorf("marshal of item %d of %s: %w", i, v.Type(), err)
+			}
+		}
+		if err := m.value(v.Index(i)); err != nil {
+			return err
+		}
+		if m.lineStart {
+			return fmt.Errorf("marshal of empty item %d of %s", i, v.Type())
+		}
+		m.newline()
+	}
+	m.closeBlock()
+	return nil
+}
+
+// itemHeadPlaceholder is written as the head of slice items having no head, it is not kept when they are decoded
+const itemHeadPlaceholder = "-"
+
+// itemHead writes the head of a slice item. It is taken from a field tagged with head option or from Head method, the
+// placeholder is written for items having no head
+func (m *caddyCfgMarshaler) itemHead(v reflect.Value) error {
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Ptr {
+		// nil items are reported by the value marshaling
+		return nil
+	}
+
+	var head string
+	index, err := findHeadField(v.Type(), defaultTagName)
+	if err != nil {
+		return err
+	}
+	if index != nil {
+		head = v.FieldByIndex(index).String()
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	if h, ok := ptr.Interface().(interface{ Head() string }); ok && len(head) == 0 {
+		head = h.Head()
+	}
+	if len(head) == 0 {
+		head = itemHeadPlaceholder
+	}
+	return m.text(head)
+}
+
+// genericSlice writes items of a generic tree slice as arguments, a map can only be the last of them as it is written
+// as a block
+func (m *caddyCfgMarshaler) genericSlice(v reflect.Value) error {
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		for item.Kind() == reflect.Interface && !item.IsNil() {
+			item = item.Elem()
+		}
+		if item.Kind() == reflect.Map && i < v.Len()-1 {
+			return fmt.Errorf("marshal of %s: a map can only be the last item", v.Type())
+		}
+		if err := m.value(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *caddyCfgMarshaler) mapping(v reflect.Value) error {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return lessMapKey(keys[i], keys[j])
+	})
+
+	m.openBlock()
+	for _, key := range keys {
+		if err := m.entry(fmt.Sprint(key.Interface()), v.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+	m.closeBlock()
+	return nil
+}
+
+func (m *caddyCfgMarshaler) structure(ptr reflect.Value) error {
+	v := ptr.Elem()
+
+	argFields, err := createArgIndex(v.Type(), defaultTagName)
+	if err != nil {
+		return err
+	}
+
+	var optionalBlock bool
+	switch args := ptr.Interface().(type) {
+	case ArgumentsConsumer:
+		optionalBlock = true
+		if err := m.arguments(args.Arguments()); err != nil {
+			return err
+		}
+	case ArgumentsCollector:
+		optionalBlock = true
+		if err := m.arguments(args.Arguments()); err != nil {
+			return err
+		}
+	case argumentAccess:
+		// the block is optional with fields bound to arguments, they are set from the same arguments
+		optionalBlock = len(argFields) > 0
+		if err := m.arguments(args.Arguments()); err != nil {
+			return err
+		}
+	default:
+		optionalBlock = len(argFields) > 0
+		for _, field := range argFields {
+			if err := m.value(v.FieldByIndex(field.index)); err != nil {
+				return fmt.Errorf("marshal %s: %s", fieldPath(v.Type(), field.index), err)
+			}
+		}
+	}
+
+	index := map[string][]int{}
+	if err := createStructIndex(index, v, nil, defaultTagName); err != nil {
+		return err
+	}
+	var names []string
+	for _, name := range orderFields(index) {
+		field := v.FieldByIndex(index[name])
+		if (field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface) && field.IsNil() {
+			continue
+		}
+		if field.IsZero() && fieldTagByIndex(v.Type(), index[name], defaultTagName).has("omitempty") {
+			continue
+		}
+		names = append(names, name)
+	}
+	if optionalBlock && len(names) == 0 {
+		return nil
+	}
+
+	m.openBlock()
+	for _, name := range names {
+		field := v.FieldByIndex(index[name])
+		tag := fieldTagByIndex(v.Type(), index[name], defaultTagName)
+		if !tag.has("repeat") {
+			m.heads = tag.has("heads")
+			err := m.entry(name, field)
+			m.heads = false
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// an entry per item for repeated keys
+		for field.Kind() == reflect.Ptr {
+			field = field.Elem()
+		}
+		if field.Kind() != reflect.Slice {
+			return fmt.Errorf("marshal %s: repeat tag option is only supported for slices", name)
+		}
+		for i := 0; i < field.Len(); i++ {
+			if err := m.entry(name, field.Index(i)); err != nil {
+				return err
+			}
+		}
+	}
+	m.closeBlock()
+	return nil
+}
+
+// isPrimitiveType checks if values of type t are represented with exactly one token
+func isPrimitiveType(t reflect.Type) bool {
+	if isRegisteredType(t) {
+		return true
+	}
+	if implementsUnmarshaler(t, textUnmarshalerType) || implementsUnmarshaler(t, jsonUnmarshalerType) {
+		return true
+	}
+	if reflect.PtrTo(t).Implements(textMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return true
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case
+		reflect.Bool,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	default:
+		return false
+	}
+}
+
+func lessMapKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		return a.Int() < b.Int()
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return a.Uint() < b.Uint()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	case reflect.String:
+		return a.String() < b.String()
+	default:
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	}
+}
+
+// quoteToken returns a representation of value which the Caddyfile lexer will read back as value
+func quoteToken(value string) (string, error) {
+	if value == "{" || value == "}" {
+		return "", fmt.Errorf("value %s cannot be represented as it is a block delimiter", value)
+	}
+
+	// a backslash at the end of a line joins the next line to it
+	if strings.HasSuffix(value, `\`) {
+		return "", fmt.Errorf("value %q cannot be represented as it ends with a backslash", value)
+	}
+
+	// the lexer drops a backslash before a quote at the start of unquoted token
+	needQuotes := value == "" || strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "`") ||
+		strings.HasPrefix(value, `\"`) || strings.HasPrefix(value, "\\`")
+	for _, r := range value {
+		if unicode.IsSpace(r) || r == '#' {
+			needQuotes = true
+			break
+		}
+	}
+	if !needQuotes {
+		return value, nil
+	}
+
+	// the lexer only knows how to escape quotes, so a backslash right before a quote cannot be represented
+	if strings.Contains(value, `\"`) {
+		return "", fmt.Errorf("value %q cannot be represented with Caddyfile quoting", value)
+	}
+	return `"` + strings.Replace(value, `"`, `\"`, -1) + `"`, nil
+}
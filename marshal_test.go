@@ -0,0 +1,289 @@
+package caddycfg
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy"
+	"github.com/stretchr/testify/require"
+)
+
+type jsonMarshalerImpl struct {
+	a string
+}
+
+func (v jsonMarshalerImpl) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + v.a + `"`), nil
+}
+
+func (v *jsonMarshalerImpl) UnmarshalJSON(data []byte) error {
+	v.a = string(data[1 : len(data)-1])
+	return nil
+}
+
+func mustParseCIDR(s string) net.IPNet {
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return *ipNet
+}
+
+func TestMarshal(t *testing.T) {
+	type (
+		sub struct {
+			Args
+
+			A int    `json:"a"`
+			B string `json:"b"`
+		}
+		embedded struct {
+			C float64 `json:"c"`
+		}
+		complexStruct struct {
+			embedded
+			Sub     sub               `json:"sub"`
+			Opt     *sub              `json:"opt"`
+			Items   []string          `json:"items"`
+			Blocks  []map[string]int  `json:"blocks"`
+			Lines   [][]int           `json:"lines"`
+			Mapping map[string]string `json:"mapping"`
+			JSON    jsonMarshalerImpl `json:"json"`
+			Flag    bool              `json:"flag"`
+		}
+		sample struct {
+			name     string
+			value    interface{}
+			expected string
+			wantErr  bool
+		}
+	)
+
+	samples := []sample{
+		{
+			name:     "string",
+			value:    "value",
+			expected: "root value\n",
+		},
+		{
+			name:     "string-quoted",
+			value:    `a "quoted" value`,
+			expected: `root "a \"quoted\" value"` + "\n",
+		},
+		{
+			name:     "string-empty",
+			value:    "",
+			expected: `root ""` + "\n",
+		},
+		{
+			name:     "inline-slice",
+			value:    []int{1, 2, 3},
+			expected: "root 1 2 3\n",
+		},
+		{
+			name:     "map",
+			value:    map[int]string{2: "b", 1: "a"},
+			expected: "root {\n\t1 a\n\t2 b\n}\n",
+		},
+		{
+			name:     "arguments-consumer",
+			value:    &argConsumer{"a", "b"},
+			expected: "root a b\n",
+		},
+		{
+			name:     "struct-with-arguments",
+			value:    sub{Args: Args{data: []string{"x", "y"}}, A: 1, B: "text"},
+			expected: "root x y {\n\ta 1\n\tb text\n}\n",
+		},
+		{
+			name: "slice-item-heads",
+			value: struct {
+				Routes []sub `caddy:"routes,heads"`
+			}{Routes: []sub{
+				{Args: NewArgs("route", "/api"), A: 1},
+				{Args: NewArgs("route"), B: "static"},
+			}},
+			expected: "root {\n\troutes {\n\t\troute /api {\n\t\t\ta 1\n\t\t\tb \"\"\n\t\t}\n\t\troute {\n\t\t\ta 0\n\t\t\tb static\n\t\t}\n\t}\n}\n",
+		},
+		{
+			name:     "slice-items-without-heads",
+			value:    []sub{{Args: NewArgs("", "a", "b"), A: 1}},
+			expected: "root {\n\ta b {\n\t\ta 1\n\t\tb \"\"\n\t}\n}\n",
+		},
+		{
+			name: "complex-struct",
+			value: complexStruct{
+				embedded: embedded{C: 1.5},
+				Sub:      sub{A: 12},
+				Items:    []string{"a", "b"},
+				Blocks:   []map[string]int{{"a": 1}, {"b": 2}},
+				Lines:    [][]int{{1, 2}, {3}},
+				Mapping:  map[string]string{"key": "value"},
+				JSON:     jsonMarshalerImpl{a: "json"},
+				Flag:     true,
+			},
+			expected: `root {
+	c 1.5
+	sub {
+		a 12
+		b ""
+	}
+	items a b
+	blocks {
+		{
+			a 1
+		}
+		{
+			b 2
+		}
+	}
+	lines {
+		1 2
+		3
+	}
+	mapping {
+		key value
+	}
+	json "\"json\""
+	flag true
+}
+`,
+		},
+		{
+			name: "struct-caddy-tags",
+			value: struct {
+				A int    `caddy:"a,omitempty" json:"json_a"`
+				B string `json:"b,omitempty"`
+				C int    `caddy:"c"`
+				D int    `caddy:"-" json:"d"`
+			}{C: 0},
+			expected: "root {\n\tc 0\n}\n",
+		},
+		{
+			name:     "duration",
+			value:    []time.Duration{90 * time.Minute, time.Millisecond},
+			expected: "root 1h30m0s 1ms\n",
+		},
+		{
+			name:     "text-marshaler",
+			value:    []net.IP{net.ParseIP("::1"), net.ParseIP("10.0.0.1")},
+			expected: "root ::1 10.0.0.1\n",
+		},
+		{
+			name: "registered-types",
+			value: struct {
+				URL *url.URL  `json:"url"`
+				Net net.IPNet `json:"net"`
+			}{URL: &url.URL{Scheme: "https", Host: "example.com"}, Net: mustParseCIDR("10.0.0.0/8")},
+			expected: "root {\n\turl https://example.com\n\tnet 10.0.0.0/8\n}\n",
+		},
+		{
+			name: "argument-fields",
+			value: struct {
+				Host   string   `caddy:"host,arg=0"`
+				Port   int      `caddy:"port,arg=1"`
+				Tags   []string `caddy:"tags,arg=2..."`
+				Weight int      `caddy:"weight,omitempty"`
+			}{Host: "example.com", Port: 80, Tags: []string{"a", "b"}},
+			expected: "root example.com 80 a b\n",
+		},
+		{
+			name: "slice-item-argument-fields",
+			value: struct {
+				Items []struct {
+					Path string `caddy:"path,arg=0"`
+					X    int    `caddy:"x"`
+				} `caddy:"items,heads"`
+			}{Items: []struct {
+				Path string `caddy:"path,arg=0"`
+				X    int    `caddy:"x"`
+			}{{Path: "/api", X: 1}}},
+			expected: "root {\n\titems {\n\t\t- /api {\n\t\t\tx 1\n\t\t}\n\t}\n}\n",
+		},
+		{
+			name: "slice-item-heads-not-set",
+			value: struct {
+				Routes []sub `caddy:"routes,heads"`
+			}{Routes: []sub{{Args: NewArgs("", "/api"), A: 1}}},
+			expected: "root {\n\troutes {\n\t\t- /api {\n\t\t\ta 1\n\t\t\tb \"\"\n\t\t}\n\t}\n}\n",
+		},
+		{
+			name: "slice-item-head-field",
+			value: []struct {
+				Kind string `caddy:"kind,head"`
+				Path string `caddy:"path,arg=0"`
+				X    int    `caddy:"x"`
+			}{{Kind: "route", Path: "/api", X: 1}, {Kind: "redir", Path: "/old"}},
+			expected: "root {\n\troute /api {\n\t\tx 1\n\t}\n\tredir /old {\n\t\tx 0\n\t}\n}\n",
+		},
+		{
+			name:     "string-backslash",
+			value:    `a\b`,
+			expected: `root a\b` + "\n",
+		},
+		{
+			name:     "string-backslash-before-backtick",
+			value:    "\\`q",
+			expected: "root \"\\`q\"\n",
+		},
+		{
+			name:    "error-trailing-backslash",
+			value:   `C:\dir\`,
+			wantErr: true,
+		},
+		{
+			name:    "error-backslash-before-quote",
+			value:   `\"q`,
+			wantErr: true,
+		},
+		{
+			name:    "error-block-delimiter",
+			value:   []string{"{"},
+			wantErr: true,
+		},
+		{
+			name:    "error-unsupported-type",
+			value:   make(chan int),
+			wantErr: true,
+		},
+		{
+			name:    "error-empty-blocked-slice-item",
+			value:   [][]int{{1}, {}},
+			wantErr: true,
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			data, err := Marshal("root", s.value)
+			if err != nil {
+				if !s.wantErr {
+					t.Error(err)
+				}
+				return
+			}
+			if err == nil && s.wantErr {
+				t.Errorf("error expected")
+				return
+			}
+			require.Equal(t, s.expected, string(data))
+
+			// marshaled data must unmarshal into the same value
+			dest := reflect.New(reflect.TypeOf(s.value))
+			c := caddy.NewTestController("http", string(data))
+			if err := Unmarshal(c, dest.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			require.Equal(t, s.value, dest.Elem().Interface())
+
+			dest = reflect.New(reflect.TypeOf(s.value))
+			if err := UnmarshalString(string(data), dest.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			require.Equal(t, s.value, dest.Elem().Interface())
+		})
+	}
+}
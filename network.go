@@ -0,0 +1,66 @@
+package caddycfg
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+)
+
+func init() {
+	RegisterDecoder(reflect.TypeOf(net.IP{}), func(t Token) (interface{}, error) {
+		ip := net.ParseIP(t.Value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %s", t.Value)
+		}
+		return ip, nil
+	})
+	RegisterDecoder(reflect.TypeOf(net.IPNet{}), func(t Token) (interface{}, error) {
+		_, ipNet, err := net.ParseCIDR(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return *ipNet, nil
+	})
+	RegisterDecoder(reflect.TypeOf(netip.Addr{}), func(t Token) (interface{}, error) {
+		return netip.ParseAddr(t.Value)
+	})
+	RegisterDecoder(reflect.TypeOf(netip.AddrPort{}), func(t Token) (interface{}, error) {
+		return netip.ParseAddrPort(t.Value)
+	})
+	RegisterDecoder(reflect.TypeOf(netip.Prefix{}), func(t Token) (interface{}, error) {
+		return netip.ParsePrefix(t.Value)
+	})
+	RegisterDecoder(reflect.TypeOf(HostPort{}), func(t Token) (interface{}, error) {
+		return ParseHostPort(t.Value)
+	})
+}
+
+// HostPort network address in host:port form. Host can be either a name or an IP address, IPv6 addresses are to be
+// enclosed in square brackets. Host is empty for addresses like :8080
+type HostPort struct {
+	Host string
+	Port uint16
+}
+
+// ParseHostPort parses host:port address
+func ParseHostPort(s string) (HostPort, error) {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return HostPort{}, err
+	}
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return HostPort{}, fmt.Errorf("invalid port %s of address %s", port, s)
+	}
+	return HostPort{
+		Host: host,
+		Port: uint16(portNumber),
+	}, nil
+}
+
+// String ...
+func (hp HostPort) String() string {
+	return net.JoinHostPort(hp.Host, strconv.Itoa(int(hp.Port)))
+}
@@ -0,0 +1,120 @@
+package caddycfg
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkTypes(t *testing.T) {
+	type config struct {
+		IP       net.IP                `json:"ip"`
+		Allow    []netip.Prefix        `json:"allow"`
+		Deny     []net.IPNet           `json:"deny"`
+		DNS      netip.Addr            `json:"dns"`
+		Upstream netip.AddrPort        `json:"upstream"`
+		Listen   HostPort              `json:"listen"`
+		Backends []HostPort            `json:"backends"`
+		Weights  map[netip.Addr]int    `json:"weights"`
+		Names    map[HostPort]string   `json:"names"`
+		Nets     map[netip.Prefix]bool `json:"nets"`
+	}
+
+	var cfg config
+	err := UnmarshalString(`root {
+    ip 192.168.0.1
+    allow 10.0.0.0/8 192.168.0.0/16
+    deny 10.1.0.0/16
+    dns ::1
+    upstream [::1]:53
+    listen :8080
+    backends localhost:80 10.0.0.2:8080 [::1]:443
+    weights {
+        10.0.0.1 1
+        10.0.0.2 2
+    }
+    names {
+        example.com:443 main
+    }
+    nets {
+        fd00::/8 true
+    }
+}`, &cfg)
+	require.NoError(t, err)
+
+	require.Equal(t, net.ParseIP("192.168.0.1"), cfg.IP)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.0.0/16")}, cfg.Allow)
+	require.Equal(t, []net.IPNet{mustParseCIDR("10.1.0.0/16")}, cfg.Deny)
+	require.Equal(t, netip.MustParseAddr("::1"), cfg.DNS)
+	require.Equal(t, netip.MustParseAddrPort("[::1]:53"), cfg.Upstream)
+	require.Equal(t, HostPort{Port: 8080}, cfg.Listen)
+	require.Equal(t, []HostPort{{Host: "localhost", Port: 80}, {Host: "10.0.0.2", Port: 8080}, {Host: "::1", Port: 443}}, cfg.Backends)
+	require.Equal(t, map[netip.Addr]int{netip.MustParseAddr("10.0.0.1"): 1, netip.MustParseAddr("10.0.0.2"): 2}, cfg.Weights)
+	require.Equal(t, map[HostPort]string{{Host: "example.com", Port: 443}: "main"}, cfg.Names)
+	require.Equal(t, map[netip.Prefix]bool{netip.MustParsePrefix("fd00::/8"): true}, cfg.Nets)
+
+	// marshaled values unmarshal back
+	data, err := Marshal("root", cfg)
+	require.NoError(t, err)
+	var restored config
+	require.NoError(t, UnmarshalString(string(data), &restored))
+	require.Equal(t, cfg, restored)
+}
+
+func TestNetworkTypesErrors(t *testing.T) {
+	type (
+		config struct {
+			IP     net.IP         `json:"ip"`
+			Allow  []netip.Prefix `json:"allow"`
+			Listen HostPort       `json:"listen"`
+		}
+		sample struct {
+			name   string
+			input  string
+			errMsg string
+			col    int
+		}
+	)
+
+	samples := []sample{
+		{
+			name:   "ip",
+			input:  "root {\n  ip 10.0.0.256\n}",
+			errMsg: "Caddyfile:2: cannot unmarshal into net.IP: invalid IP address 10.0.0.256",
+			col:    6,
+		},
+		{
+			name:   "prefix-in-slice",
+			input:  "root {\n  allow 10.0.0.0/8 10.0.0.0/33\n}",
+			errMsg: `Caddyfile:2: cannot unmarshal into netip.Prefix: netip.ParsePrefix("10.0.0.0/33"): prefix length out of range`,
+			col:    20,
+		},
+		{
+			name:   "host-port-missing-port",
+			input:  "root {\n  listen localhost\n}",
+			errMsg: "Caddyfile:2: cannot unmarshal into caddycfg.HostPort: address localhost: missing port in address",
+			col:    10,
+		},
+		{
+			name:   "host-port-invalid-port",
+			input:  "root {\n  listen localhost:http\n}",
+			errMsg: "Caddyfile:2: cannot unmarshal into caddycfg.HostPort: invalid port http of address localhost:http",
+			col:    10,
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			var cfg config
+			err := UnmarshalString(s.input, &cfg)
+			require.EqualError(t, err, s.errMsg)
+			var te *TokenError
+			require.True(t, errors.As(err, &te))
+			require.Equal(t, s.col, te.Col)
+			require.ErrorIs(t, err, ErrInvalidValue)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package caddycfg
+
+import (
+	"reflect"
+)
+
+// Option sets up a decoding policy of a Decoder
+type Option func(*options)
+
+type options struct {
+	allowUnknownKeys     bool
+	allowTrailingData    bool
+	allowDuplicateKeys   bool
+	requireAllFields     bool
+	collectErrors        bool
+	requireBoolValues    bool
+	allowBoolAliases     bool
+	allowIntegerLiterals bool
+	resolveColumns       bool
+	tagName              string
+	normalizeKey         func(string) string
+	decoders             map[reflect.Type]DecodeFunc
+}
+
+func newOptions(opts []Option) options {
+	res := options{
+		tagName: defaultTagName,
+	}
+	for _, opt := range opts {
+		opt(&res)
+	}
+	return res
+}
+
+// AllowUnknownKeys sets if unknown keys of struct blocks are to be skipped (with their arguments and blocks)
+// instead of being reported as errors. Unknown keys are not allowed by default
+func AllowUnknownKeys(allow bool) Option {
+	return func(o *options) {
+		o.allowUnknownKeys = allow
+	}
+}
+
+// AllowTrailingData sets if data left after the plugin config is to be ignored instead of being reported as
+// an error. Trailing data is not allowed by default
+func AllowTrailingData(allow bool) Option {
+	return func(o *options) {
+		o.allowTrailingData = allow
+	}
+}
+
+// AllowDuplicateKeys sets if a non-repeatable key of struct block can be set more than once, the last value wins in
+// this case. Duplicate keys are not allowed by default. Slice fields are always repeatable: every occurrence of their
+// key appends items. This doesn't affect maps: their keys must always be unique
+func AllowDuplicateKeys(allow bool) Option {
+	return func(o *options) {
+		o.allowDuplicateKeys = allow
+	}
+}
+
+// RequireAllFields sets if every struct field is required to be set, unless it is tagged with optional option.
+// Only fields tagged with required option are required by default
+func RequireAllFields(require bool) Option {
+	return func(o *options) {
+		o.requireAllFields = require
+	}
+}
+
+// CollectErrors sets if decoding is to proceed after errors in block entries, skipping the rest of an entry line
+// together with a block it opens. All errors are returned as MultiError then
+func CollectErrors(collect bool) Option {
+	return func(o *options) {
+		o.collectErrors = collect
+	}
+}
+
+// RequireBoolValues sets if bool values must be given explicitly. A bool key with no value on its line is set to true
+// by default, e.g. a line with just gzip is the same as gzip true
+func RequireBoolValues(require bool) Option {
+	return func(o *options) {
+		o.requireBoolValues = require
+	}
+}
+
+// AllowBoolAliases sets if on/off, yes/no and 1/0 are accepted as bool values in addition to true/false. They are not
+// accepted by default
+func AllowBoolAliases(allow bool) Option {
+	return func(o *options) {
+		o.allowBoolAliases = allow
+	}
+}
+
+// AllowIntegerLiterals sets if integers can be written with Go integer literal syntax: with 0x, 0o, 0b base prefixes
+// and _ digit separators, e.g. 0xff or 1_000_000. Note a leading 0 means octal then, so 0644 is 420. Only decimal
+// integers are accepted by default
+func AllowIntegerLiterals(allow bool) Option {
+	return func(o *options) {
+		o.allowIntegerLiterals = allow
+	}
+}
+
+// ResolveColumns sets if columns of caddy.Controller tokens are to be resolved, the controller only gives their lines.
+// Source files are read and tokenized for this. Tokens changed by Caddy, e.g. with {$ENV} placeholders replaced, are
+// not found in the source and keep zero columns. Columns are not resolved by default, RenderError finds them when
+// errors are rendered anyway
+func ResolveColumns(resolve bool) Option {
+	return func(o *options) {
+		o.resolveColumns = resolve
+	}
+}
+
+// TagName sets a name of struct tag to take key names and options from, it is "caddy" by default. The json tag
+// is used for fields without this tag
+func TagName(name string) Option {
+	return func(o *options) {
+		o.tagName = name
+	}
+}
+
+// KeyNormalizer sets a function to be applied to both struct keys and config keys before they are matched,
+// e.g. strings.ToLower for case insensitive keys
+func KeyNormalizer(normalize func(string) string) Option {
+	return func(o *options) {
+		o.normalizeKey = normalize
+	}
+}
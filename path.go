@@ -0,0 +1,25 @@
+package caddycfg
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Path file system path. Relative paths are resolved against the directory of the config file they are set in
+type Path string
+
+// String ...
+func (p Path) String() string {
+	return string(p)
+}
+
+func resolvePath(t Token) (Path, error) {
+	if t.Value == "" {
+		return "", fmt.Errorf("empty path")
+	}
+	path := t.Value
+	if !filepath.IsAbs(path) && t.File != "" {
+		path = filepath.Join(filepath.Dir(t.File), path)
+	}
+	return Path(filepath.Clean(path)), nil
+}
@@ -0,0 +1,116 @@
+package caddycfg
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// RenderError renders err the way compilers do: every token error is written as file:line:col: message followed
+// with the source line it points to and the token underlined with carets:
+//
+//	Caddyfile:2:7: strconv.Atoi: parsing "80x": invalid syntax
+//	 2 | 	port 80x
+//	   | 	     ^^^
+//
+// Each error of MultiError is rendered this way, errors without token position are written as is. Source files are
+// read with readFile, ioutil.ReadFile is used when it is nil. Only the error position is written if the source line
+// cannot be read. Columns missing in errors are taken from the first token of the line with the same value
+func RenderError(err error, readFile func(name string) ([]byte, error)) string {
+	if err == nil {
+		return ""
+	}
+	if readFile == nil {
+		readFile = ioutil.ReadFile
+	}
+
+	r := &errorRenderer{
+		readFile: readFile,
+		files:    map[string][]string{},
+		columns:  newColumnResolver(readFile),
+	}
+	errs := []error{err}
+	if multi, ok := err.(MultiError); ok {
+		errs = multi
+	}
+	for _, err := range errs {
+		r.render(err)
+	}
+	return r.buf.String()
+}
+
+type errorRenderer struct {
+	buf      strings.Builder
+	readFile func(name string) ([]byte, error)
+	files    map[string][]string
+	columns  *columnResolver
+}
+
+func (r *errorRenderer) render(err error) {
+	var te *TokenError
+	if !errors.As(err, &te) {
+		r.buf.WriteString(err.Error())
+		r.buf.WriteByte('\n')
+		return
+	}
+
+	t := te.Token
+	if t.Col == 0 {
+		// tokens of caddy.Controller have no columns unless they are resolved while decoding
+		r.columns.lookup(&t)
+	}
+	if t.Col > 0 {
+		fmt.Fprintf(&r.buf, "%s:%d:%d: %s\n", te.File, te.Lin, t.Col, te.Err)
+	} else {
+		fmt.Fprintf(&r.buf, "%s:%d: %s\n", te.File, te.Lin, te.Err)
+	}
+
+	lines := r.lines(te.File)
+	if te.Lin < 1 || te.Lin > len(lines) {
+		return
+	}
+	line := []rune(lines[te.Lin-1])
+	number := strconv.Itoa(te.Lin)
+	gutter := strings.Repeat(" ", len(number)+1)
+	fmt.Fprintf(&r.buf, " %s | %s\n", number, string(line))
+	if t.Col < 1 || t.Col > len(line) {
+		return
+	}
+
+	// the underline repeats tabs of the source line to stay aligned with it whatever the tab width is
+	var underline strings.Builder
+	for _, ch := range line[:t.Col-1] {
+		if ch == '\t' {
+			underline.WriteByte('\t')
+		} else {
+			underline.WriteByte(' ')
+		}
+	}
+	end := t.EndCol
+	if end > len(line)+1 || strings.Contains(t.Value, "\n") {
+		// the token continues on the next lines
+		end = len(line) + 1
+	}
+	width := end - t.Col
+	if width < 1 {
+		width = 1
+	}
+	underline.WriteString(strings.Repeat("^", width))
+	fmt.Fprintf(&r.buf, "%s | %s\n", gutter, underline.String())
+}
+
+// lines returns lines of the file, it is nil if the file cannot be read
+func (r *errorRenderer) lines(name string) []string {
+	if lines, ok := r.files[name]; ok {
+		return lines
+	}
+	var lines []string
+	if data, err := r.readFile(name); err == nil {
+		text := strings.TrimPrefix(string(data), "\ufeff")
+		lines = strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	}
+	r.files[name] = lines
+	return lines
+}
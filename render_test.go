@@ -0,0 +1,124 @@
+package caddycfg
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamColumns(t *testing.T) {
+	input := "root a {\n\tkey \"quoted value\" a\n}"
+	c := caddy.NewTestController("http", input)
+	s := newStream(c, newColumnResolver(func(name string) ([]byte, error) {
+		return []byte(input), nil
+	}))
+
+	var tokens []Token
+	for s.Next() {
+		tokens = append(tokens, s.Token())
+		s.Confirm()
+	}
+	require.Equal(t, []Token{
+		{File: "Testfile", Value: "root", Lin: 1, Col: 1, EndCol: 5},
+		{File: "Testfile", Value: "a", Lin: 1, Col: 6, EndCol: 7},
+		{File: "Testfile", Value: "{", Lin: 1, Col: 8, EndCol: 9},
+		{File: "Testfile", Value: "key", Lin: 2, Col: 2, EndCol: 5},
+		{File: "Testfile", Value: "quoted value", Lin: 2, Col: 6, EndCol: 20},
+		{File: "Testfile", Value: "a", Lin: 2, Col: 21, EndCol: 22},
+		{File: "Testfile", Value: "}", Lin: 3, Col: 1, EndCol: 2},
+	}, tokens)
+
+	// columns are not resolved by default
+	c = caddy.NewTestController("http", input)
+	s = NewStream(c).(*streamImpl)
+	require.True(t, s.Next())
+	require.Equal(t, Token{File: "Testfile", Value: "root", Lin: 1}, s.Token())
+
+	// columns are unknown when there is no source file
+	c = caddy.NewTestController("http", input)
+	d := NewDecoder(c, ResolveColumns(true))
+	var dest struct{}
+	require.Error(t, d.Decode(&dest))
+	require.Equal(t, Token{File: "Testfile", Value: "root", Lin: 1}, d.HeadToken())
+}
+
+func TestRenderError(t *testing.T) {
+	source := "root {\n\tport 80x\n\tkey \"a\nb\"\n}\n"
+	readFile := func(name string) ([]byte, error) {
+		if name != "Caddyfile" {
+			return nil, os.ErrNotExist
+		}
+		return []byte(source), nil
+	}
+
+	err := MultiError{
+		&TokenError{
+			Token: Token{File: "Caddyfile", Value: "80x", Lin: 2, Col: 7, EndCol: 10},
+			Kind:  KindInvalidValue,
+			Err:   errors.New(`strconv.Atoi: parsing "80x": invalid syntax`),
+		},
+		&TokenError{
+			Token: Token{File: "Caddyfile", Value: "a\nb", Lin: 3, Col: 6, EndCol: 3},
+			Kind:  KindCustom,
+			Err:   errors.New("multiline value"),
+		},
+		&TokenError{
+			Token: Token{File: "Caddyfile", Value: "{", Lin: 1},
+			Kind:  KindCustom,
+			Err:   errors.New("column from source"),
+		},
+		&TokenError{
+			Token: Token{File: "Caddyfile", Value: "replaced", Lin: 1},
+			Kind:  KindCustom,
+			Err:   errors.New("no column"),
+		},
+		&TokenError{
+			Token: Token{File: "Other", Value: "x", Lin: 1, Col: 1, EndCol: 2},
+			Kind:  KindCustom,
+			Err:   errors.New("no source"),
+		},
+		errors.New("no position"),
+	}
+
+	expected := "Caddyfile:2:7: strconv.Atoi: parsing \"80x\": invalid syntax\n" +
+		" 2 | \tport 80x\n" +
+		"   | \t     ^^^\n" +
+		"Caddyfile:3:6: multiline value\n" +
+		" 3 | \tkey \"a\n" +
+		"   | \t    ^^\n" +
+		"Caddyfile:1:6: column from source\n" +
+		" 1 | root {\n" +
+		"   |      ^\n" +
+		"Caddyfile:1: no column\n" +
+		" 1 | root {\n" +
+		"Other:1:1: no source\n" +
+		"no position\n"
+	require.Equal(t, expected, RenderError(err, readFile))
+	require.Equal(t, "", RenderError(nil, readFile))
+}
+
+func TestRenderDecodeError(t *testing.T) {
+	source := "root {\n\tport 80x\n}\n"
+	readFile := func(name string) ([]byte, error) {
+		return []byte(source), nil
+	}
+
+	var dest struct {
+		Port int `caddy:"port"`
+	}
+	err := UnmarshalString(source, &dest)
+	require.Error(t, err)
+	expected := "Caddyfile:2:7: strconv.Atoi: parsing \"80x\": invalid syntax\n" +
+		" 2 | \tport 80x\n" +
+		"   | \t     ^^^\n"
+	require.Equal(t, expected, RenderError(err, readFile))
+
+	// columns of controller tokens are found in the source
+	err = Unmarshal(caddy.NewTestController("http", source), &dest)
+	require.Error(t, err)
+	require.Equal(t, strings.Replace(expected, "Caddyfile", "Testfile", 1), RenderError(err, readFile))
+}
@@ -1,6 +1,8 @@
 package caddycfg
 
 import (
+	"strings"
+
 	"github.com/caddyserver/caddy"
 )
 
@@ -19,15 +21,22 @@ type Stream interface {
 
 type streamImpl struct {
 	src       *caddy.Controller
+	columns   *columnResolver
 	cur       Token
 	finished  bool
 	confirmed bool
 }
 
-// newStream return stream implementation
-func newStream(src *caddy.Controller) Stream {
+// NewStream returns a stream of the controller tokens. Their columns are unknown, as caddy.Controller only gives lines
+func NewStream(src *caddy.Controller) Stream {
+	return newStream(src, nil)
+}
+
+// newStream returns a stream of the controller tokens, their columns are resolved with columns if it is not nil
+func newStream(src *caddy.Controller, columns *columnResolver) *streamImpl {
 	return &streamImpl{
 		src:       src,
+		columns:   columns,
 		confirmed: true,
 	}
 }
@@ -41,9 +50,7 @@ func (t *streamImpl) Next() bool {
 		return true
 	}
 	if t.src.Next() {
-		t.cur.File = t.src.File()
-		t.cur.Value = t.src.Val()
-		t.cur.Lin = t.src.Line()
+		t.read()
 		t.confirmed = false
 		return true
 	}
@@ -60,15 +67,25 @@ func (t *streamImpl) NextArg() bool {
 		return true
 	}
 	if t.src.NextArg() {
-		t.cur.File = t.src.File()
-		t.cur.Value = t.src.Val()
-		t.cur.Lin = t.src.Line()
+		t.read()
 		t.confirmed = false
 		return true
 	}
 	return false
 }
 
+// read takes current token of the controller
+func (t *streamImpl) read() {
+	t.cur = Token{
+		File:  t.src.File(),
+		Value: t.src.Val(),
+		Lin:   t.src.Line(),
+	}
+	if t.columns != nil {
+		t.columns.resolve(&t.cur)
+	}
+}
+
 // Token ...
 func (t *streamImpl) Token() Token {
 	return t.cur
@@ -78,3 +95,111 @@ func (t *streamImpl) Token() Token {
 func (t *streamImpl) Confirm() {
 	t.confirmed = true
 }
+
+// sliceStream stream over the given tokens, it follows caddy.Controller semantics of Next and NextArg
+type sliceStream struct {
+	tokens    []Token
+	cursor    int
+	confirmed bool
+}
+
+// NewSliceStream returns a stream of the given tokens, tokens of a line must have the same File and Lin
+func NewSliceStream(tokens []Token) Stream {
+	return &sliceStream{
+		tokens:    tokens,
+		cursor:    -1,
+		confirmed: true,
+	}
+}
+
+// Next checks if next token is available
+func (s *sliceStream) Next() bool {
+	if !s.confirmed {
+		return true
+	}
+	if s.cursor < len(s.tokens)-1 {
+		s.cursor++
+		s.confirmed = false
+		return true
+	}
+	return false
+}
+
+// NextArg checks if next token at current row is available
+func (s *sliceStream) NextArg() bool {
+	if !s.confirmed {
+		return true
+	}
+	if s.cursor < 0 && len(s.tokens) > 0 {
+		s.cursor++
+		s.confirmed = false
+		return true
+	}
+	if s.cursor < 0 || s.cursor >= len(s.tokens)-1 {
+		return false
+	}
+	if !sameLine(s.tokens[s.cursor], s.tokens[s.cursor+1]) {
+		return false
+	}
+	s.cursor++
+	s.confirmed = false
+	return true
+}
+
+// Token ...
+func (s *sliceStream) Token() Token {
+	if s.cursor < 0 || s.cursor >= len(s.tokens) {
+		return Token{}
+	}
+	return s.tokens[s.cursor]
+}
+
+// Confirm ...
+func (s *sliceStream) Confirm() {
+	s.confirmed = true
+}
+
+// sameLine checks if next token follows prev on the same line, prev may span several lines being quoted
+func sameLine(prev, next Token) bool {
+	return prev.File == next.File && prev.Lin+strings.Count(prev.Value, "\n") == next.Lin
+}
+
+// depthStream tracks block depth of the underlying stream, this is needed to skip rest of entries after errors
+type depthStream struct {
+	Stream
+	depth   int
+	pending bool
+	eof     bool
+}
+
+// Next ...
+func (s *depthStream) Next() bool {
+	s.pending = s.Stream.Next()
+	if !s.pending {
+		s.eof = true
+	}
+	return s.pending
+}
+
+// NextArg ...
+func (s *depthStream) NextArg() bool {
+	s.pending = s.Stream.NextArg()
+	return s.pending
+}
+
+// Confirm ...
+func (s *depthStream) Confirm() {
+	if s.pending {
+		s.depth += blockDepthChange(s.Token())
+		s.pending = false
+	}
+	s.Stream.Confirm()
+}
+
+// blockDepth returns current block depth if the stream tracks it
+func blockDepth(s Stream) int {
+	if ds, ok := s.(*depthStream); ok {
+		return ds.depth
+	}
+	return 0
+}
@@ -1,9 +1,11 @@
 package caddycfg
 
 import (
+	"os"
+	"testing"
+
 	"github.com/caddyserver/caddy"
 	"github.com/stretchr/testify/require"
-	"testing"
 )
 
 func TestStreamer(t *testing.T) {
@@ -11,7 +13,42 @@ func TestStreamer(t *testing.T) {
          root {
              a 1234
          }`)
-	s := newStream(c)
+	s := NewStream(c)
+
+	require.True(t, s.NextArg())
+	require.True(t, s.NextArg())
+	require.Equal(t, s.Token().Value, "root")
+	s.Confirm()
+	require.True(t, s.NextArg())
+	require.Equal(t, s.Token().Value, "{")
+	s.Confirm()
+	require.False(t, s.NextArg())
+	require.Equal(t, s.Token().Value, "{")
+	require.True(t, s.Next())
+	require.True(t, s.Next())
+	require.Equal(t, s.Token().Value, "a")
+	s.Confirm()
+	require.True(t, s.Next())
+	require.Equal(t, s.Token().Value, "1234")
+	s.Confirm()
+	require.False(t, s.NextArg())
+	require.True(t, s.Next())
+	require.Equal(t, s.Token().Value, "}")
+	s.Confirm()
+	require.False(t, s.NextArg())
+	require.False(t, s.Next())
+	require.False(t, s.Next())
+	require.False(t, s.NextArg())
+}
+
+func TestSliceStream(t *testing.T) {
+	s := NewSliceStream([]Token{
+		{Value: "root", Lin: 1},
+		{Value: "{", Lin: 1},
+		{Value: "a", Lin: 2},
+		{Value: "1234", Lin: 2},
+		{Value: "}", Lin: 3},
+	})
 
 	require.True(t, s.NextArg())
 	require.True(t, s.NextArg())
@@ -38,3 +75,46 @@ func TestStreamer(t *testing.T) {
 	require.False(t, s.Next())
 	require.False(t, s.NextArg())
 }
+
+// envStream custom stream expanding environment variables in token values
+type envStream struct {
+	Stream
+}
+
+func (s envStream) Token() Token {
+	t := s.Stream.Token()
+	t.Value = os.ExpandEnv(t.Value)
+	return t
+}
+
+func TestUnmarshalStream(t *testing.T) {
+	type config struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+
+	require.NoError(t, os.Setenv("CADDYCFG_TEST_PORT", "8080"))
+	defer os.Unsetenv("CADDYCFG_TEST_PORT")
+
+	tokens, err := tokenize("Caddyfile", []byte(`plugin {
+    name a
+    port ${CADDYCFG_TEST_PORT}
+}
+other`))
+	require.NoError(t, err)
+
+	s := envStream{Stream: NewSliceStream(tokens)}
+	require.True(t, s.NextArg())
+	head := s.Token()
+	s.Confirm()
+
+	var cfg config
+	require.NoError(t, UnmarshalStream(head, s, &cfg))
+	require.Equal(t, config{Name: "a", Port: 8080}, cfg)
+
+	// the rest of the stream is left for the caller
+	require.True(t, s.Next())
+	require.Equal(t, "other", s.Token().Value)
+
+	require.Error(t, UnmarshalStream(head, s, cfg))
+}
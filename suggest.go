@@ -0,0 +1,113 @@
+package caddycfg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const maxSuggestions = 3
+
+// UnknownKeyError describes unknown key of a struct block, it is an underlying error of TokenError of KindUnknownKey
+type UnknownKeyError struct {
+	Key string
+
+	// Suggestions allowed keys close to the Key, the closest come first
+	Suggestions []string
+
+	// Allowed all allowed keys in order of fields
+	Allowed []string
+}
+
+// Error ...
+func (e *UnknownKeyError) Error() string {
+	quote := func(names []string) []string {
+		res := make([]string, len(names))
+		for i, name := range names {
+			res[i] = fmt.Sprintf("%q", name)
+		}
+		return res
+	}
+
+	if len(e.Suggestions) > 0 {
+		return fmt.Sprintf("unknown key %q, did you mean %s?", e.Key, strings.Join(quote(e.Suggestions), " or "))
+	}
+	switch len(e.Allowed) {
+	case 0:
+		return fmt.Sprintf("it has no fields to store config data, got field %q", e.Key)
+	case 1:
+		return fmt.Sprintf("unknown key %q, only this one is allowed - %s", e.Key, quote(e.Allowed)[0])
+	default:
+		return fmt.Sprintf("unknown key %q, only these are allowed - %s", e.Key, strings.Join(quote(e.Allowed), ", "))
+	}
+}
+
+// suggestKeys returns names close enough to the key, the closest come first
+func suggestKeys(key string, names []string) []string {
+	threshold := len([]rune(key)) / 3
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	for _, name := range names {
+		if distance := editDistance(key, name); distance <= threshold {
+			candidates = append(candidates, candidate{name: name, distance: distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	if len(candidates) > maxSuggestions {
+		candidates = candidates[:maxSuggestions]
+	}
+
+	var res []string
+	for _, c := range candidates {
+		res = append(res, c.name)
+	}
+	return res
+}
+
+// editDistance computes Damerau-Levenshtein distance (optimal string alignment variant) between a and b, so
+// a transposition of adjacent characters costs 1 just like an insertion, deletion or substitution
+func editDistance(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(first int, rest ...int) int {
+	res := first
+	for _, value := range rest {
+		if value < res {
+			res = value
+		}
+	}
+	return res
+}
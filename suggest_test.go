@@ -0,0 +1,66 @@
+package caddycfg
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/caddyserver/caddy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{a: "", b: "", want: 0},
+		{a: "timeout", b: "timeout", want: 0},
+		{a: "tiemout", b: "timeout", want: 1},
+		{a: "timeot", b: "timeout", want: 1},
+		{a: "timeouts", b: "timeout", want: 1},
+		{a: "tymeout", b: "timeout", want: 1},
+		{a: "kitten", b: "sitting", want: 3},
+		{a: "", b: "abc", want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"-"+tt.b, func(t *testing.T) {
+			require.Equal(t, tt.want, editDistance(tt.a, tt.b))
+			require.Equal(t, tt.want, editDistance(tt.b, tt.a))
+		})
+	}
+}
+
+func TestSuggestKeys(t *testing.T) {
+	names := []string{"timeout", "read_timeout", "timeouts", "upstream", "host"}
+	require.Equal(t, []string{"timeout", "timeouts"}, suggestKeys("tiemout", names))
+	require.Equal(t, []string{"host"}, suggestKeys("hots", names))
+	require.Empty(t, suggestKeys("completely_different", names))
+}
+
+func TestUnknownKeySuggestions(t *testing.T) {
+	type config struct {
+		Timeout  int    `caddy:"timeout"`
+		Upstream string `caddy:"upstream"`
+		Host     string `caddy:"host"`
+	}
+
+	c := caddy.NewTestController("http", `root {
+        tiemout 1
+    }`)
+	var dest config
+	err := Unmarshal(c, &dest)
+	require.EqualError(t, err, `Testfile:2: unmarshal into caddycfg.config: unknown key "tiemout", did you mean "timeout"?`)
+
+	var uke *UnknownKeyError
+	require.True(t, errors.As(err, &uke))
+	require.Equal(t, "tiemout", uke.Key)
+	require.Equal(t, []string{"timeout"}, uke.Suggestions)
+	require.Equal(t, []string{"timeout", "upstream", "host"}, uke.Allowed)
+
+	c = caddy.NewTestController("http", `root {
+        something 1
+    }`)
+	err = Unmarshal(c, &dest)
+	require.EqualError(t, err, `Testfile:2: unmarshal into caddycfg.config: unknown key "something", only these are allowed - "timeout", "upstream", "host"`)
+}
@@ -0,0 +1,75 @@
+package caddycfg
+
+import (
+	"reflect"
+	"strings"
+)
+
+const (
+	defaultTagName  = "caddy"
+	fallbackTagName = "json"
+)
+
+// fieldTag struct field tag in a form of
+//
+//	caddy:"name,option₁,option₂=value,…"
+//
+// the value of an option is an empty string when there's no =
+type fieldTag struct {
+	name    string
+	options map[string]string
+	skip    bool
+}
+
+// has checks if a tag has the given option
+func (t fieldTag) has(option string) bool {
+	_, ok := t.options[option]
+	return ok
+}
+
+func parseTag(tag string) fieldTag {
+	if tag == "-" {
+		return fieldTag{skip: true}
+	}
+	parts := strings.Split(tag, ",")
+	res := fieldTag{
+		name: parts[0],
+	}
+	for _, part := range parts[1:] {
+		if len(part) == 0 {
+			continue
+		}
+		if res.options == nil {
+			res.options = map[string]string{}
+		}
+		pos := strings.IndexByte(part, '=')
+		if pos < 0 {
+			res.options[part] = ""
+			continue
+		}
+		res.options[part[:pos]] = part[pos+1:]
+	}
+	return res
+}
+
+// lookupFieldTag looks for a tag with the given name and falls back to the json tag if there is no one. A key name
+// is taken from the json tag too when the tag itself doesn't set it
+func lookupFieldTag(field reflect.StructField, tagName string) (fieldTag, bool) {
+	if len(tagName) == 0 {
+		tagName = defaultTagName
+	}
+	jsonTag, hasJSONTag := field.Tag.Lookup(fallbackTagName)
+	tag, ok := field.Tag.Lookup(tagName)
+	if !ok {
+		if !hasJSONTag {
+			return fieldTag{}, false
+		}
+		return parseTag(jsonTag), true
+	}
+
+	res := parseTag(tag)
+	if len(res.name) == 0 && !res.skip && hasJSONTag {
+		res.name = parseTag(jsonTag).name
+	}
+	return res, true
+}
@@ -0,0 +1,123 @@
+package caddycfg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want fieldTag
+	}{
+		{
+			name: "name-only",
+			tag:  "key",
+			want: fieldTag{name: "key"},
+		},
+		{
+			name: "skip",
+			tag:  "-",
+			want: fieldTag{skip: true},
+		},
+		{
+			name: "options",
+			tag:  "key,omitempty,default=30s",
+			want: fieldTag{
+				name: "key",
+				options: map[string]string{
+					"omitempty": "",
+					"default":   "30s",
+				},
+			},
+		},
+		{
+			name: "no-name",
+			tag:  ",omitempty",
+			want: fieldTag{
+				options: map[string]string{
+					"omitempty": "",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, parseTag(tt.tag))
+		})
+	}
+}
+
+func TestLookupFieldTag(t *testing.T) {
+	type sample struct {
+		Caddy    int `caddy:"caddy_key,omitempty" json:"json_caddy"`
+		JSON     int `json:"json_key,omitempty"`
+		NoName   int `caddy:",omitempty" json:"json_name"`
+		Skip     int `caddy:"-" json:"json_skip"`
+		JSONSkip int `caddy:"caddy_key" json:"-"`
+		Custom   int `yaml:"yaml_key" json:"json_custom"`
+		None     int
+	}
+
+	tests := []struct {
+		name    string
+		field   string
+		tagName string
+		want    fieldTag
+		wantOK  bool
+	}{
+		{
+			name:   "caddy-precedence",
+			field:  "Caddy",
+			want:   fieldTag{name: "caddy_key", options: map[string]string{"omitempty": ""}},
+			wantOK: true,
+		},
+		{
+			name:   "json-fallback",
+			field:  "JSON",
+			want:   fieldTag{name: "json_key", options: map[string]string{"omitempty": ""}},
+			wantOK: true,
+		},
+		{
+			name:   "json-name-fallback",
+			field:  "NoName",
+			want:   fieldTag{name: "json_name", options: map[string]string{"omitempty": ""}},
+			wantOK: true,
+		},
+		{
+			name:   "caddy-skip",
+			field:  "Skip",
+			want:   fieldTag{skip: true},
+			wantOK: true,
+		},
+		{
+			name:   "json-skip-overridden",
+			field:  "JSONSkip",
+			want:   fieldTag{name: "caddy_key"},
+			wantOK: true,
+		},
+		{
+			name:    "custom-tag-name",
+			field:   "Custom",
+			tagName: "yaml",
+			want:    fieldTag{name: "yaml_key"},
+			wantOK:  true,
+		},
+		{
+			name:   "no-tags",
+			field:  "None",
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, _ := reflect.TypeOf(sample{}).FieldByName(tt.field)
+			got, ok := lookupFieldTag(field, tt.tagName)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package caddycfg
 
-// Token config token. Gives token location (Col is not supported currently)
+// Token config token. Gives token location: Lin is a line number, Col and EndCol are 1-based rune columns of the
+// token start and right after its end (quotes included). Columns are 0 when they are unknown, e.g. when the source
+// file of the token cannot be read
 type Token struct {
-	File  string
-	Value string
-	Lin   int
-	Col   int
+	File   string
+	Value  string
+	Lin    int
+	Col    int
+	EndCol int
 }
 
 // String ...
@@ -1,8 +1,11 @@
 package caddycfg
 
 import (
+	"encoding"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"reflect"
 	"strings"
 
@@ -11,74 +14,113 @@ import (
 
 // UnmarshalHeadInfo returns token with plugin name and unmarshal c into dest
 func UnmarshalHeadInfo(c *caddy.Controller, dest interface{}) (Token, error) {
-	destValue := reflect.ValueOf(dest)
-	var head Token
+	decoder := NewDecoder(c)
+	err := decoder.Decode(dest)
+	return decoder.HeadToken(), err
+}
 
-	if destValue.Type().Kind() != reflect.Ptr {
-		return head, fmt.Errorf("unmarshal into non-pointer %T", dest)
-	}
+// Unmarshal unmarshaller into dest, which must not be channel
+func Unmarshal(c *caddy.Controller, dest interface{}) error {
+	_, err := UnmarshalHeadInfo(c, dest)
+	return err
+}
 
-	stream := newStream(c)
-	if !stream.NextArg() {
-		// plugin name is expected
-		return head, fmt.Errorf("got no config data for plugin at line %d", c.Line())
+// UnmarshalStream unmarshal an entry with the given head into dest. The head must be consumed already, i.e. s is to
+// be positioned right after it. Only tokens of the entry are consumed, the rest of the stream is left for the caller.
+// Options of the current decoding are used when it is called with the stream given to CaddyUnmarshaler
+func UnmarshalStream(head Token, s Stream, dest interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Type().Kind() != reflect.Ptr {
+		return fmt.Errorf("unmarshal into non-pointer %T", dest)
 	}
-	head = stream.Token()
-	unmarshaler := &caddyCfgUnmarshaler{
-		headToken: head,
+	if us, ok := s.(*unmarshalerStream); ok {
+		// called from CaddyUnmarshaler, go on with the current decoding
+		return us.c.unmarshal(head, us.Stream, destValue.Elem())
 	}
-	stream.Confirm()
+	decoder := NewStreamDecoder(s)
+	decoder.head = head
+	return decoder.decode(destValue.Elem(), false)
+}
 
-	err := unmarshaler.unmarshal(head, stream, destValue.Elem())
-	if err != nil {
-		return head, err
-	}
+// UnmarshalString unmarshal plugin config given in Caddyfile syntax into dest. Tokens are reported to be in the
+// file named Caddyfile
+func UnmarshalString(src string, dest interface{}) error {
+	return unmarshalSource("Caddyfile", []byte(src), dest)
+}
 
-	if stream.Next() {
-		return head, TokenErrorf(stream.Token(), "got unexpected data '%s' for plugin '%s'", stream.Token(), unmarshaler.headToken)
+// UnmarshalReader unmarshal plugin config read from r in Caddyfile syntax into dest. name is a file name of tokens
+func UnmarshalReader(name string, r io.Reader, dest interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
 	}
+	return unmarshalSource(name, data, dest)
+}
 
-	return head, nil
+// UnmarshalTokens unmarshal plugin config tokens into dest
+func UnmarshalTokens(tokens []Token, dest interface{}) error {
+	return NewTokensDecoder(tokens).Decode(dest)
 }
 
-// Unmarshal unmarshaller into dest, which must not be channel
-func Unmarshal(c *caddy.Controller, dest interface{}) error {
-	_, err := UnmarshalHeadInfo(c, dest)
-	return err
+func unmarshalSource(name string, src []byte, dest interface{}) error {
+	tokens, err := tokenize(name, src)
+	if err != nil {
+		return err
+	}
+	return UnmarshalTokens(tokens, dest)
 }
 
 type caddyCfgUnmarshaler struct {
+	options
 	headToken Token
+	errs      []error
+	path      []string
+
+	// tag of the struct field being decoded, its options apply to the field value and its items
+	tag fieldTag
+
+	// midLine is set when the key of the entry being decoded doesn't start its line, it can't be a flag then
+	midLine bool
+
+	// headedItem is set when a slice item with a head is to be decoded, processStruct keeps the head in the item
+	headedItem bool
 }
 
 func (c *caddyCfgUnmarshaler) unmarshal(head Token, s Stream, v reflect.Value) (err error) {
 	// If input v implements Validator
+	errCount := len(c.errs)
 	defer func() {
-		if err != nil {
-			return
-		}
-		s.NextArg()
-		if validator, ok := v.Interface().(Validator); ok {
-			if nerr := validator.Err(head); nerr != nil {
-				err = nerr
+		if err == nil && len(c.errs) == errCount {
+			// do not validate values with errors collected in them
+			s.NextArg()
+			if validator, ok := v.Interface().(Validator); ok {
+				if nerr := validator.Err(head); nerr != nil {
+					err = nerr
+				}
 			}
 		}
+		if te, ok := err.(*TokenError); ok && len(te.Path) == 0 {
+			te.Path = c.currentPath()
+		}
 	}()
 
-	// types itself can be JSONUmarshaler too
-	referenceType, isJSONUnmarshaler := refType(v.Type())
+	if typ, decode := c.lookupDecoder(v.Type()); decode != nil {
+		return c.processRegistered(s, v, typ, decode)
+	}
+	if implementsUnmarshaler(v.Type(), caddyUnmarshalerType) {
+		return c.processCaddyUnmarshaler(head, s, v)
+	}
 
-	if isJSONUnmarshaler {
+	// types decoding single token values themselves, either a type or a pointer to it can implement these.
+	// encoding.TextUnmarshaler takes precedence over json.Unmarshaler as it gets a token value as is
+	if implementsUnmarshaler(v.Type(), textUnmarshalerType) {
+		return c.processTextUnmarshaler(s, v)
+	}
+	if implementsUnmarshaler(v.Type(), jsonUnmarshalerType) {
 		return c.processJSONUnmarshaler(s, v)
 	}
 
-	// point to type can be JSONUnmarshaler, check it
-	if v.CanAddr() {
-		ptr := v.Addr()
-		if _, isJSONUnmarshaler := refType(ptr.Type()); isJSONUnmarshaler {
-			return c.processPointerJSONUnmarshaler(s, ptr)
-		}
-	}
+	referenceType, _ := refType(v.Type())
 
 	if _, ok := v.Addr().Interface().(ArgumentsCollector); ok && referenceType.Kind() != reflect.Struct {
 		return c.processBlockArguments(s, v.Addr())
@@ -87,6 +129,17 @@ func (c *caddyCfgUnmarshaler) unmarshal(head Token, s Stream, v reflect.Value) (
 		return c.consumeBlockArguments(s, v.Addr())
 	}
 
+	if c.tag.has("bytes") && isNumericKind(referenceType.Kind()) {
+		return c.processByteSizeField(s, v)
+	}
+	if c.tag.has("rate") && isNumericKind(referenceType.Kind()) {
+		return c.processRateField(s, v)
+	}
+
+	if referenceType == durationType {
+		return c.processDuration(s, v)
+	}
+
 	switch referenceType.Kind() {
 	case reflect.Bool:
 		return c.processBoolean(s, v)
@@ -117,43 +170,73 @@ func (c *caddyCfgUnmarshaler) unmarshal(head Token, s Stream, v reflect.Value) (
 	case reflect.String:
 		return c.processString(s, v)
 	case reflect.Slice:
-		return c.processSlice(s, v)
+		return c.processSlice(s, v, nil)
+	case reflect.Array:
+		return c.processArray(head, s, v)
+	case reflect.Interface:
+		return c.processInterface(s, v)
 	case reflect.Map:
 		return c.processMap(s, v)
 	case reflect.Struct:
-		return c.processStruct(s, v)
+		return c.processStruct(head, s, v)
 	default:
-		return TokenErrorf(c.headToken, "unmarshal into %s is not supported", referenceType)
+		return tokenErrorf(c.headToken, KindUnsupportedType, "unmarshal into %s is not supported", referenceType)
 	}
 }
 
-func (c *caddyCfgUnmarshaler) processStruct(s Stream, v reflect.Value) error {
+func (c *caddyCfgUnmarshaler) processStruct(head Token, s Stream, v reflect.Value) error {
 	r := refValue(v)
-	if !s.NextArg() {
-		return TokenErrorf(c.headToken, "unmarshal into %s: no data", r.Type())
+	argFields, err := createArgIndex(r.Type(), c.tagName)
+	if err != nil {
+		return err
+	}
+	if _, err := findHeadField(r.Type(), c.tagName); err != nil {
+		return err
+	}
+	headedItem := c.headedItem
+	c.headedItem = false
+	if !s.NextArg() && len(argFields) == 0 {
+		return tokenErrorf(c.headToken, KindNoData, "unmarshal into %s: no data", r.Type())
 	}
 	nr := reflect.New(r.Type())
+	if headedItem && head.Value != itemHeadPlaceholder {
+		// the head is set before decoding to be available for validators
+		setItemHead(nr, head.Value, c.tagName)
+	}
+
+	// create structure index
+	index, err := c.structIndex(r)
+	if err != nil {
+		return err
+	}
+
 	prevToken := s.Token()
-	if prevToken.Value != "{" {
-		if err := c.dealWithBlockArguments(c.headToken, s, nr); err != nil {
+	openToken := prevToken
+	if len(argFields) > 0 {
+		if err := c.bindArguments(head, s, nr, argFields); err != nil {
+			if _, ok := err.(noBlock); ok {
+				return c.setStruct(openToken, r, nr, index, nil)
+			}
+			return err
+		}
+		openToken = s.Token()
+	} else if prevToken.Value != "{" {
+		if err := c.dealWithBlockArguments(head, s, nr); err != nil {
 			if _, ok := err.(noBlock); ok {
-				r.Set(nr.Elem())
-				return nil
+				return c.setStruct(openToken, r, nr, index, nil)
 			}
 			return err
 		}
+		openToken = s.Token()
 	} else {
 		s.Confirm()
 	}
-	// create structure index
-	index := map[string][]int{}
-	if err := createStructIndex(index, r, nil); err != nil {
-		return err
-	}
 
 	// scanning values
 	var closed bool
-	for s.Next() {
+	depth := blockDepth(s)
+	keysTaken := map[string]Token{}
+	for last := s.Token(); s.Next(); last = s.Token() {
 		t := s.Token()
 		prevToken = t
 		s.Confirm()
@@ -162,42 +245,311 @@ func (c *caddyCfgUnmarshaler) processStruct(s Stream, v reflect.Value) error {
 			break
 		}
 
-		key := t.Value
+		key := c.normalize(t.Value)
 		fieldIndex, isKnownField := index[key]
+		if !isKnownField && c.allowUnknownKeys {
+			skipEntry(s)
+			continue
+		}
 		if !isKnownField {
-			names := orderFields(index)
-			for i, name := range names {
-				names[i] = fmt.Sprintf("'%s'", name)
-			}
-			switch len(names) {
-			case 0:
-				return TokenErrorf(t, "unmarshal into %s: it has no fields to store config data, got field %s", r.Type(), key)
-			case 1:
-				return TokenErrorf(t, "unmarshal into %s: unknown key %s, only this one is allowed - %s", r.Type(), key, names[0])
-			default:
-				return TokenErrorf(t, "unmarshal into %s: unknown key %s, only these are allowed - %s", r.Type(), key, strings.Join(names, ", "))
+			err := unknownKeyError(t, r.Type(), key, index)
+			if c.recoverFrom(s, t, err, depth) {
+				continue
 			}
+			return err
 		}
 		s.Confirm()
 
 		fff := nr.Elem().FieldByIndex(fieldIndex)
-		if err := c.unmarshal(prevToken, s, fff); err != nil {
+		repeatable := c.isRepeatable(fff.Type(), fieldTagByIndex(r.Type(), fieldIndex, c.tagName))
+		if prevKeyToken, alreadyTaken := keysTaken[key]; alreadyTaken && !repeatable && !c.allowDuplicateKeys {
+			err := tokenErrorf(t, KindDuplicateKey,
+				"unmarshal into %s: duplicate key %s, it has already been set at %s:%d",
+				r.Type(),
+				t.Value,
+				prevKeyToken.File,
+				prevKeyToken.Lin,
+			)
+			if c.recoverFrom(s, t, err, depth) {
+				continue
+			}
+			return err
+		}
+		if _, alreadyTaken := keysTaken[key]; !alreadyTaken {
+			keysTaken[key] = t
+		}
+
+		prevTag := c.enterField(r.Type(), fieldIndex)
+		prevMidLine := c.midLine
+		c.midLine = sameLine(last, t)
+		var err error
+		if repeatable {
+			err = c.appendField(prevToken, s, fff)
+		} else {
+			err = c.unmarshal(prevToken, s, fff)
+		}
+		c.midLine = prevMidLine
+		if err == nil {
+			err = c.checkLineEnd(s, t)
+		}
+		c.leaveField(prevTag)
+		if err != nil {
+			if c.recoverFrom(s, t, err, depth) {
+				continue
+			}
 			return err
 		}
 	}
 
 	if !closed {
-		return TokenErrorf(prevToken, "unmarshal into %s: { expected", r.Type())
+		return tokenErrorf(prevToken, KindUnclosedBlock, "unmarshal into %s: { expected", r.Type())
+	}
+
+	return c.setStruct(openToken, r, nr, index, keysTaken)
+}
+
+// checkLineEnd reports data left on the line after the value of an entry started with key. It must be called before
+// the path of the entry is left, so that the error is reported at the entry
+func (c *caddyCfgUnmarshaler) checkLineEnd(s Stream, key Token) error {
+	if !s.NextArg() {
+		return nil
+	}
+	t := s.Token()
+	err := tokenErrorf(t, KindUnexpectedData, "unexpected data '%s' after the value of %s", t, key.Value)
+	err.(*TokenError).Path = c.currentPath()
+	return err
+}
+
+// isRepeatable checks if a struct field of type t can be set more than once, every occurrence adds items to it then.
+// These are fields tagged with repeat option and slices other than ones decoded from a single token
+func (c *caddyCfgUnmarshaler) isRepeatable(t reflect.Type, tag fieldTag) bool {
+	if tag.has("repeat") {
+		return true
+	}
+	rt := t
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Slice {
+		return false
+	}
+	if _, decode := c.lookupDecoder(t); decode != nil {
+		return false
+	}
+	for _, iface := range []reflect.Type{caddyUnmarshalerType, textUnmarshalerType, jsonUnmarshalerType} {
+		if implementsUnmarshaler(t, iface) {
+			return false
+		}
+	}
+	switch reflect.New(rt).Interface().(type) {
+	case ArgumentsConsumer, ArgumentsCollector:
+		return false
+	}
+	return true
+}
+
+// appendField decodes an occurrence of a repeatable field and appends its items to the field value. An occurrence
+// is a single item for fields tagged with repeat option and a slice of items otherwise
+func (c *caddyCfgUnmarshaler) appendField(head Token, s Stream, v reflect.Value) error {
+	l := v
+	for l.Kind() == reflect.Ptr {
+		if l.IsNil() {
+			l.Set(reflect.New(l.Type().Elem()))
+		}
+		l = l.Elem()
+	}
+	if l.Kind() != reflect.Slice {
+		return tokenErrorf(head, KindUnsupportedType, "unmarshal into %s: repeat tag option is only supported for slices", v.Type())
+	}
+
+	if c.tag.has("repeat") {
+		item := reflect.New(l.Type().Elem()).Elem()
+		c.pushPath(fmt.Sprintf("[%d]", l.Len()))
+		err := c.unmarshal(head, s, item)
+		c.popPath()
+		if err != nil {
+			return err
+		}
+		if s.NextArg() {
+			// every occurrence is exactly one item
+			t := s.Token()
+			return tokenErrorf(t, KindUnexpectedData, "unmarshal into %s: repeated key %s takes a single item, got extra %s", v.Type(), head.Value, t.Value)
+		}
+		l.Set(reflect.Append(l, item))
+		return nil
 	}
 
+	items := reflect.New(l.Type()).Elem()
+	if err := c.unmarshal(head, s, items); err != nil {
+		return err
+	}
+	l.Set(reflect.AppendSlice(l, items))
+	return nil
+}
+
+func unknownKeyError(t Token, structType reflect.Type, key string, index map[string][]int) error {
+	names := orderFields(index)
+	return tokenErrorf(t, KindUnknownKey, "unmarshal into %s: %w", structType, &UnknownKeyError{
+		Key:         key,
+		Suggestions: suggestKeys(key, names),
+		Allowed:     names,
+	})
+}
+
+// pushPath adds an item to the path of value being decoded, it is either a field name or an index in brackets
+func (c *caddyCfgUnmarshaler) pushPath(item string) {
+	c.path = append(c.path, item)
+}
+
+func (c *caddyCfgUnmarshaler) popPath() {
+	c.path = c.path[:len(c.path)-1]
+}
+
+// enterField adds the field to the path and makes its tag the current one, the previous tag is returned
+func (c *caddyCfgUnmarshaler) enterField(structType reflect.Type, index []int) fieldTag {
+	prev := c.tag
+	c.pushPath(fieldPath(structType, index))
+	c.tag = fieldTagByIndex(structType, index, c.tagName)
+	return prev
+}
+
+// leaveField reverts enterField
+func (c *caddyCfgUnmarshaler) leaveField(prev fieldTag) {
+	c.popPath()
+	c.tag = prev
+}
+
+// currentPath returns Go path of value being decoded, e.g. Key1.Sub[2]
+func (c *caddyCfgUnmarshaler) currentPath() string {
+	var buf strings.Builder
+	for _, item := range c.path {
+		if buf.Len() > 0 && !strings.HasPrefix(item, "[") {
+			buf.WriteByte('.')
+		}
+		buf.WriteString(item)
+	}
+	return buf.String()
+}
+
+// recoverFrom collects err caused by an entry started with token t at the given block depth and skips the rest of
+// the entry if errors are to be collected. Returns false if the decoding cannot proceed
+func (c *caddyCfgUnmarshaler) recoverFrom(s Stream, t Token, err error, depth int) bool {
+	ds, ok := s.(*depthStream)
+	if !c.collectErrors || !ok || ds.eof {
+		return false
+	}
+
+	te, ok := err.(*TokenError)
+	if !ok {
+		te = &TokenError{
+			Token: t,
+			Err:   err,
+		}
+	}
+	if len(te.Path) == 0 {
+		te.Path = c.currentPath()
+	}
+	c.errs = append(c.errs, te)
+
+	if ds.depth == depth {
+		skipEntry(s)
+		return true
+	}
+	// the error happened inside of the entry's block
+	for ds.depth > depth && s.Next() {
+		s.Confirm()
+	}
+	return true
+}
+
+// collected returns errors collected during decoding together with the err as MultiError
+func (c *caddyCfgUnmarshaler) collected(err error) error {
+	errs := c.errs
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	sortErrors(errs)
+	return MultiError(errs)
+}
+
+// setStruct checks if all required keys were set, applies default values of absent keys and sets r with decoded
+// structure value nr points to
+func (c *caddyCfgUnmarshaler) setStruct(openToken Token, r, nr reflect.Value, index map[string][]int, keysTaken map[string]Token) error {
+	var missing []string
+	for _, name := range orderFields(index) {
+		if _, ok := keysTaken[name]; ok {
+			continue
+		}
+		tag := fieldTagByIndex(r.Type(), index[name], c.tagName)
+		if defaultValue, ok := tag.options["default"]; ok {
+			prevTag := c.enterField(r.Type(), index[name])
+			err := c.setDefault(openToken, name, defaultValue, nr.Elem().FieldByIndex(index[name]))
+			c.leaveField(prevTag)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !c.isRequired(tag) {
+			continue
+		}
+		missing = append(missing, fmt.Sprintf("'%s' (%s)", name, fieldPath(r.Type(), index[name])))
+	}
+
+	// the value is set even with keys missing as the decoding may proceed collecting errors
 	r.Set(nr.Elem())
+	switch len(missing) {
+	case 0:
+		return nil
+	case 1:
+		return tokenErrorf(openToken, KindMissingKey, "unmarshal into %s: missing required key %s", r.Type(), missing[0])
+	default:
+		return tokenErrorf(openToken, KindMissingKey, "unmarshal into %s: missing required keys %s", r.Type(), strings.Join(missing, ", "))
+	}
+}
+
+// setDefault decodes default value of the key into v just like it was given in a config at the open token position
+func (c *caddyCfgUnmarshaler) setDefault(openToken Token, key string, value string, v reflect.Value) error {
+	head := Token{
+		File:  openToken.File,
+		Value: key,
+		Lin:   openToken.Lin,
+	}
+	var tokens []Token
+	for _, item := range strings.Fields(value) {
+		t := head
+		t.Value = item
+		tokens = append(tokens, t)
+	}
+
+	stream := NewSliceStream(tokens)
+	err := c.unmarshal(head, stream, v)
+	if err == nil && stream.Next() {
+		err = fmt.Errorf("unexpected data '%s'", stream.Token())
+	}
+	if err != nil {
+		if te, ok := err.(*TokenError); ok {
+			err = te.Err
+		}
+		return tokenErrorf(openToken, KindInvalidValue, "unmarshal into %s: invalid default value of key '%s': %s", v.Type(), key, err)
+	}
 	return nil
 }
 
+func (c *caddyCfgUnmarshaler) isRequired(tag fieldTag) bool {
+	if tag.has("required") {
+		return true
+	}
+	return c.requireAllFields && !tag.has("optional")
+}
+
 func (c *caddyCfgUnmarshaler) processBlockArguments(s Stream, v reflect.Value) error {
 	r := refValue(v.Elem())
 	if !s.NextArg() {
-		return TokenErrorf(c.headToken, "unmarshal into %s: no data", r.Type())
+		return tokenErrorf(c.headToken, KindNoData, "unmarshal into %s: no data", r.Type())
 	}
 	nr := reflect.New(r.Type())
 	args := nr.Interface().(ArgumentsCollector)
@@ -205,7 +557,7 @@ func (c *caddyCfgUnmarshaler) processBlockArguments(s Stream, v reflect.Value) e
 		t := s.Token()
 		s.Confirm()
 		if t.Value == "{" {
-			return TokenErrorf(t, "unmarshal into %s: unexpected {", v.Type().Elem())
+			return tokenErrorf(t, KindUnexpectedBlock, "unmarshal into %s: unexpected {", v.Type().Elem())
 		}
 		if err := args.AppendArgument(t); err != nil {
 			return err
@@ -218,7 +570,7 @@ func (c *caddyCfgUnmarshaler) processBlockArguments(s Stream, v reflect.Value) e
 func (c *caddyCfgUnmarshaler) consumeBlockArguments(s Stream, v reflect.Value) error {
 	r := refValue(v.Elem())
 	if !s.NextArg() {
-		return TokenErrorf(c.headToken, "unmarshal into %s: no data", r.Type())
+		return tokenErrorf(c.headToken, KindNoData, "unmarshal into %s: no data", r.Type())
 	}
 	nr := reflect.New(r.Type())
 	args := nr.Interface().(ArgumentsConsumer)
@@ -227,7 +579,7 @@ func (c *caddyCfgUnmarshaler) consumeBlockArguments(s Stream, v reflect.Value) e
 		t := s.Token()
 		s.Confirm()
 		if t.Value == "{" {
-			return TokenErrorf(t, "unmarshal into %s: unexpected {", v.Type().Elem())
+			return tokenErrorf(t, KindUnexpectedBlock, "unmarshal into %s: unexpected {", v.Type().Elem())
 		}
 		tokens = append(tokens, t)
 	}
@@ -238,6 +590,148 @@ func (c *caddyCfgUnmarshaler) consumeBlockArguments(s Stream, v reflect.Value) e
 	return nil
 }
 
+// structIndex creates an index of struct fields with key names normalized
+func (c *caddyCfgUnmarshaler) structIndex(v reflect.Value) (map[string][]int, error) {
+	index := map[string][]int{}
+	if err := createStructIndex(index, v, nil, c.tagName); err != nil {
+		return nil, err
+	}
+	if c.normalizeKey == nil {
+		return index, nil
+	}
+
+	normalized := make(map[string][]int, len(index))
+	for name, fieldIndex := range index {
+		key := c.normalizeKey(name)
+		if _, ok := normalized[key]; ok {
+			return nil, fmt.Errorf("%s has several fields with key %s after normalization", v.Type(), key)
+		}
+		normalized[key] = fieldIndex
+	}
+	return normalized, nil
+}
+
+func (c *caddyCfgUnmarshaler) normalize(key string) string {
+	if c.normalizeKey == nil {
+		return key
+	}
+	return c.normalizeKey(key)
+}
+
+// skipEntry skips the rest of the current line together with a block it may open
+func skipEntry(s Stream) {
+	var depth int
+	for s.NextArg() {
+		depth += blockDepthChange(s.Token())
+		s.Confirm()
+	}
+	for depth > 0 && s.Next() {
+		depth += blockDepthChange(s.Token())
+		s.Confirm()
+	}
+}
+
+func blockDepthChange(t Token) int {
+	switch t.Value {
+	case "{":
+		return 1
+	case "}":
+		return -1
+	default:
+		return 0
+	}
+}
+
+// bindArguments decodes positional arguments of the head line into fields bound to them, v is a pointer to the struct.
+// Types taking arguments themselves get all of them too. The block is optional
+func (c *caddyCfgUnmarshaler) bindArguments(head Token, s Stream, v reflect.Value, fields []argField) error {
+	var tokens []Token
+	var opened bool
+	for s.NextArg() {
+		t := s.Token()
+		s.Confirm()
+		if t.Value == "{" {
+			opened = true
+			break
+		}
+		tokens = append(tokens, t)
+	}
+
+	structType := v.Type().Elem()
+	for _, field := range fields {
+		var args []Token
+		switch {
+		case field.pos < len(tokens) && field.variadic:
+			args = tokens[field.pos:]
+		case field.pos < len(tokens):
+			args = tokens[field.pos : field.pos+1]
+		case field.variadic || fieldTagByIndex(structType, field.index, c.tagName).has("optional"):
+			continue
+		default:
+			t := head
+			if len(tokens) > 0 {
+				t = tokens[len(tokens)-1]
+			}
+			return tokenErrorf(t, KindNoData, "unmarshal into %s: missing argument %d (%s)",
+				structType, field.pos, fieldPath(structType, field.index))
+		}
+
+		prevTag := c.enterField(structType, field.index)
+		err := c.bindArgument(args, v.Elem().FieldByIndex(field.index))
+		c.leaveField(prevTag)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch v.Interface().(type) {
+	case ArgumentsCollector, ArgumentsConsumer:
+		// they check arguments themselves
+	default:
+		if last := fields[len(fields)-1]; !last.variadic && len(tokens) > len(fields) {
+			t := tokens[len(fields)]
+			return tokenErrorf(t, KindUnexpectedData, "unmarshal into %s: unexpected argument %s", structType, t.Value)
+		}
+	}
+
+	var values []string
+	for _, t := range tokens {
+		values = append(values, t.Value)
+	}
+	switch argAcc := v.Interface().(type) {
+	case ArgumentsCollector:
+		for _, t := range tokens {
+			if err := argAcc.AppendArgument(t); err != nil {
+				return err
+			}
+		}
+	case ArgumentsConsumer:
+		if err := argAcc.ConsumeArguments(head, tokens); err != nil {
+			return err
+		}
+	case argumentAccess:
+		argAcc.appendData(values)
+	}
+
+	if !opened {
+		return noBlock{}
+	}
+	return nil
+}
+
+// bindArgument decodes argument tokens into the field value
+func (c *caddyCfgUnmarshaler) bindArgument(args []Token, v reflect.Value) error {
+	stream := NewSliceStream(args)
+	if err := c.unmarshal(args[0], stream, v); err != nil {
+		return err
+	}
+	if stream.Next() {
+		t := stream.Token()
+		return tokenErrorf(t, KindUnexpectedData, "unmarshal into %s: unexpected argument %s", v.Type(), t.Value)
+	}
+	return nil
+}
+
 type noBlock struct{}
 
 func (noBlock) Error() string {
@@ -298,17 +792,18 @@ func (c *caddyCfgUnmarshaler) dealWithBlockArguments(head Token, s Stream, v ref
 			data = append(data, t.Value)
 		}
 		if !opened {
-			return TokenErrorf(prevToken, "unmarshal into %s: { expected", v.Type().Elem())
+			return tokenErrorf(prevToken, KindBlockExpected, "unmarshal into %s: { expected", v.Type().Elem())
 		}
 		argAcc.appendData(data)
 		return nil
 	default:
-		return TokenErrorf(s.Token(), "{ expected")
+		return tokenErrorf(s.Token(), KindBlockExpected, "{ expected")
 	}
 }
 
 func (c *caddyCfgUnmarshaler) processMap(s Stream, v reflect.Value) error {
-	keyType := v.Type().Key()
+	mapType, _ := refType(v.Type())
+	keyType := mapType.Key()
 	switch keyType.String() {
 	case
 		reflect.Bool.String(),
@@ -324,15 +819,17 @@ func (c *caddyCfgUnmarshaler) processMap(s Stream, v reflect.Value) error {
 		reflect.Uint.String(),
 		reflect.String.String():
 	default:
-		rt, _ := refType(v.Type())
-		return fmt.Errorf("unmarshaling into a %s is not supported: key can only be one of integer number type, boolean and string", rt)
+		if !c.isLeafType(keyType) {
+			rt, _ := refType(v.Type())
+			return tokenErrorf(c.headToken, KindUnsupportedType, "unmarshaling into a %s is not supported: key can only be one of integer number type, boolean, string and a type decoded from a single token", rt)
+		}
 	}
 
 	if !s.NextArg() {
-		return TokenErrorf(c.headToken, "{ expected")
+		return tokenErrorf(c.headToken, KindBlockExpected, "{ expected")
 	}
 	if s.Token().Value != "{" {
-		return TokenErrorf(s.Token(), "{ was expected, got %s", s.Token())
+		return tokenErrorf(s.Token(), KindBlockExpected, "{ was expected, got %s", s.Token())
 	}
 	prevToken := s.Token()
 	s.Confirm()
@@ -341,8 +838,9 @@ func (c *caddyCfgUnmarshaler) processMap(s Stream, v reflect.Value) error {
 	dest := reflect.Zero(r.Type())
 	valueType := r.Type().Elem()
 	var closed bool
+	depth := blockDepth(s)
 	keysTaken := make(map[interface{}]Token)
-	for s.Next() {
+	for last := s.Token(); s.Next(); last = s.Token() {
 		t := s.Token()
 		prevToken = t
 		if t.Value == "}" {
@@ -353,19 +851,38 @@ func (c *caddyCfgUnmarshaler) processMap(s Stream, v reflect.Value) error {
 
 		key := reflect.New(keyType)
 		if err := c.unmarshal(prevToken, s, key.Elem()); err != nil {
+			if c.recoverFrom(s, t, err, depth) {
+				continue
+			}
 			return err
 		}
 		if prevKeyToken, alreadyTaken := keysTaken[key.Elem().Interface()]; alreadyTaken {
-			return TokenErrorf(t,
+			err := tokenErrorf(t, KindDuplicateKey,
 				"using key %s which has already been taken at %s:%d",
 				key.Elem().Interface(),
 				prevKeyToken.File,
 				prevKeyToken.Lin,
 			)
+			if c.recoverFrom(s, t, err, depth) {
+				continue
+			}
+			return err
 		}
 		keysTaken[key.Elem().Interface()] = t
 		value := reflect.New(valueType)
-		if err := c.unmarshal(prevToken, s, value.Elem()); err != nil {
+		c.pushPath(fmt.Sprintf("[%#v]", key.Elem().Interface()))
+		prevMidLine := c.midLine
+		c.midLine = sameLine(last, t)
+		err := c.unmarshal(prevToken, s, value.Elem())
+		c.midLine = prevMidLine
+		if err == nil {
+			err = c.checkLineEnd(s, t)
+		}
+		c.popPath()
+		if err != nil {
+			if c.recoverFrom(s, t, err, depth) {
+				continue
+			}
 			return err
 		}
 		if dest.IsNil() {
@@ -375,7 +892,7 @@ func (c *caddyCfgUnmarshaler) processMap(s Stream, v reflect.Value) error {
 	}
 	r.Set(dest)
 	if !closed {
-		return TokenErrorf(prevToken, "} expected")
+		return tokenErrorf(prevToken, KindUnclosedBlock, "} expected")
 	}
 	return nil
 }
@@ -390,11 +907,12 @@ func (c *caddyCfgUnmarshaler) processMap(s Stream, v reflect.Value) error {
 //           aₙ
 //        }
 // 2. Slice of complex types can only be represented as b variant
-func (c *caddyCfgUnmarshaler) processSlice(s Stream, v reflect.Value) error {
+// onItem is called with the first token of every item if it is set
+func (c *caddyCfgUnmarshaler) processSlice(s Stream, v reflect.Value, onItem func(Token)) error {
 	s.NextArg()
 	token := s.Token()
 	if token.Value == "{" {
-		return c.processBlockedSlice(s, v)
+		return c.processBlockedSlice(s, v, onItem)
 	}
 
 	r := ref(v)
@@ -402,12 +920,18 @@ func (c *caddyCfgUnmarshaler) processSlice(s Stream, v reflect.Value) error {
 	for s.NextArg() {
 		if s.Token().Value == "{" {
 			rt, _ := refType(v.Type())
-			return TokenErrorf(s.Token(), "unmarshal block with arguments into %s", rt)
+			return tokenErrorf(s.Token(), KindUnexpectedBlock, "unmarshal block with arguments into %s", rt)
+		}
+		if onItem != nil {
+			onItem(s.Token())
 		}
 		sliceElementType := l.Type().Elem()
 		sliceItem := reflect.New(sliceElementType)
 		rr := sliceItem.Elem()
-		if err := c.unmarshal(token, s, rr); err != nil {
+		c.pushPath(fmt.Sprintf("[%d]", l.Len()))
+		err := c.unmarshal(token, s, rr)
+		c.popPath()
+		if err != nil {
 			return err
 		}
 		l = reflect.Append(l, rr)
@@ -417,7 +941,7 @@ func (c *caddyCfgUnmarshaler) processSlice(s Stream, v reflect.Value) error {
 	return nil
 }
 
-func (c *caddyCfgUnmarshaler) processBlockedSlice(s Stream, v reflect.Value) error {
+func (c *caddyCfgUnmarshaler) processBlockedSlice(s Stream, v reflect.Value, onItem func(Token)) error {
 	prevToken := s.Token()
 	s.Confirm() // we reached { to be in here, so passing it
 
@@ -426,6 +950,7 @@ func (c *caddyCfgUnmarshaler) processBlockedSlice(s Stream, v reflect.Value) err
 
 	// read until closing }
 	var closed bool
+	depth := blockDepth(s)
 	for s.Next() {
 		t := s.Token()
 		prevToken = t
@@ -434,22 +959,73 @@ func (c *caddyCfgUnmarshaler) processBlockedSlice(s Stream, v reflect.Value) err
 			s.Confirm()
 			break
 		}
+		if onItem != nil {
+			onItem(t)
+		}
 		sliceElementType := l.Type().Elem()
 		sliceItem := reflect.New(sliceElementType)
 		rr := sliceItem.Elem()
-		if err := c.unmarshal(prevToken, s, rr); err != nil {
+		if t.Value != "{" && c.takesHead(sliceElementType) {
+			// the first token of the line is the item head, arguments and a block follow it
+			s.Confirm()
+			c.headedItem = true
+		}
+		c.pushPath(fmt.Sprintf("[%d]", l.Len()))
+		err := c.unmarshal(prevToken, s, rr)
+		c.popPath()
+		c.headedItem = false
+		if err != nil {
+			if c.recoverFrom(s, t, err, depth) {
+				continue
+			}
 			return err
 		}
 		l = reflect.Append(l, rr)
 	}
 	if !closed {
-		return TokenErrorf(prevToken, "} expected")
+		return tokenErrorf(prevToken, KindUnclosedBlock, "} expected")
 	}
 	r.Set(l)
 
 	return nil
 }
 
+// takesHead checks if lines of a blocked slice of items of type t start with item heads, like keys of struct fields.
+// These are structs having a field keeping the head or items of slice fields tagged with heads option
+func (c *caddyCfgUnmarshaler) takesHead(t reflect.Type) bool {
+	if _, decode := c.lookupDecoder(t); decode != nil {
+		return false
+	}
+	for _, iface := range []reflect.Type{caddyUnmarshalerType, textUnmarshalerType, jsonUnmarshalerType} {
+		if implementsUnmarshaler(t, iface) {
+			return false
+		}
+	}
+	return isHeadedType(t, c.tagName, c.tag.has("heads"))
+}
+
+// processArray decodes items just like for slices, their number must be exactly the array length
+func (c *caddyCfgUnmarshaler) processArray(head Token, s Stream, v reflect.Value) error {
+	r := ref(v)
+	items := reflect.New(reflect.SliceOf(r.Type().Elem())).Elem()
+	var tokens []Token
+	if err := c.processSlice(s, items, func(t Token) {
+		tokens = append(tokens, t)
+	}); err != nil {
+		return err
+	}
+	if len(tokens) != r.Len() {
+		t := head
+		if len(tokens) > r.Len() {
+			t = tokens[r.Len()]
+		}
+		return tokenErrorf(t, KindInvalidValue, "unmarshal into %s: expected %d values, got %d", r.Type(), r.Len(), len(tokens))
+	}
+	reflect.Copy(r, items)
+
+	return nil
+}
+
 func (c *caddyCfgUnmarshaler) processString(s Stream, v reflect.Value) error {
 	if err := c.needArgValue(s, v); err != nil {
 		return err
@@ -463,20 +1039,36 @@ func (c *caddyCfgUnmarshaler) processString(s Stream, v reflect.Value) error {
 	return nil
 }
 
+var boolAliases = map[string]bool{
+	"on":  true,
+	"off": false,
+	"yes": true,
+	"no":  false,
+	"1":   true,
+	"0":   false,
+}
+
 func (c *caddyCfgUnmarshaler) processBoolean(s Stream, v reflect.Value) error {
+	if !s.NextArg() && !c.requireBoolValues && !c.midLine {
+		// a flag key, it is set with no value
+		ref(v).SetBool(true)
+		return nil
+	}
 	if err := c.needArgValue(s, v); err != nil {
 		return err
 	}
 
 	t := s.Token()
 	r := ref(v)
-	switch t.Value {
-	case "true":
+	switch value, isAlias := boolAliases[t.Value]; {
+	case t.Value == "true":
 		r.Set(reflect.ValueOf(true))
-	case "false":
+	case t.Value == "false":
 		r.Set(reflect.ValueOf(false))
+	case isAlias && c.allowBoolAliases:
+		r.SetBool(value)
 	default:
-		return TokenErrorf(t, "true or false expected, got %s", t)
+		return tokenErrorf(t, KindInvalidValue, "true or false expected, got %s", t)
 	}
 
 	s.Confirm()
@@ -484,17 +1076,15 @@ func (c *caddyCfgUnmarshaler) processBoolean(s Stream, v reflect.Value) error {
 	return nil
 }
 
-func (c *caddyCfgUnmarshaler) processJSONUnmarshaler(s Stream, v reflect.Value) error {
+func (c *caddyCfgUnmarshaler) processTextUnmarshaler(s Stream, v reflect.Value) error {
 	if err := c.needArgValue(s, v); err != nil {
 		return err
 	}
 
 	t := s.Token()
-	r := ref(v).Interface().(json.Unmarshaler)
-	if err := r.UnmarshalJSON([]byte(t.Value)); err != nil {
-		if err = r.UnmarshalJSON([]byte(fmt.Sprintf(`"%s"`, t.Value))); err != nil {
-			return TokenErrorf(t, "cannot unmarshal: %s", err)
-		}
+	r := unmarshalerValue(v, textUnmarshalerType).Interface().(encoding.TextUnmarshaler)
+	if err := r.UnmarshalText([]byte(t.Value)); err != nil {
+		return tokenErrorf(t, KindInvalidValue, "cannot unmarshal: %s", err)
 	}
 
 	s.Confirm()
@@ -502,27 +1092,29 @@ func (c *caddyCfgUnmarshaler) processJSONUnmarshaler(s Stream, v reflect.Value)
 	return nil
 }
 
-func (c *caddyCfgUnmarshaler) processPointerJSONUnmarshaler(s Stream, v reflect.Value) error {
+func (c *caddyCfgUnmarshaler) processJSONUnmarshaler(s Stream, v reflect.Value) error {
 	if err := c.needArgValue(s, v); err != nil {
 		return err
 	}
 
 	t := s.Token()
-	v.Elem().Set(reflect.Zero(v.Elem().Type()))
-	r := v.Interface().(json.Unmarshaler)
+	r := unmarshalerValue(v, jsonUnmarshalerType).Interface().(json.Unmarshaler)
 	if err := r.UnmarshalJSON([]byte(t.Value)); err != nil {
-		if err = r.UnmarshalJSON([]byte(fmt.Sprintf(`"%s"`, t.Value))); err != nil {
-			return TokenErrorf(t, "cannot unmarshal: %s", err)
+		// token value may be a JSON string without quotes
+		quoted, _ := json.Marshal(t.Value)
+		if err = r.UnmarshalJSON(quoted); err != nil {
+			return tokenErrorf(t, KindInvalidValue, "cannot unmarshal: %s", err)
 		}
 	}
 
 	s.Confirm()
+
 	return nil
 }
 
 func (c *caddyCfgUnmarshaler) needArgValue(s Stream, v reflect.Value) error {
 	if !s.NextArg() {
-		return TokenErrorf(c.headToken, "got no data for %s", v.Type())
+		return tokenErrorf(c.headToken, KindNoData, "got no data for %s", v.Type())
 	}
 	return nil
 }
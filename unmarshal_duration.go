@@ -0,0 +1,122 @@
+package caddycfg
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (c *caddyCfgUnmarshaler) processDuration(s Stream, v reflect.Value) error {
+	if err := c.needArgValue(s, v); err != nil {
+		return err
+	}
+
+	t := s.Token()
+	r := ref(v)
+	value, err := parseDuration(t.Value)
+	if err != nil {
+		return tokenErrorf(t, KindInvalidValue, "%s", err)
+	}
+	r.Set(reflect.ValueOf(value))
+
+	s.Confirm()
+
+	return nil
+}
+
+// parseDuration parses duration just like time.ParseDuration does, with days (d) and weeks (w) units supported
+// in addition, e.g. 1w2d12h
+func parseDuration(value string) (time.Duration, error) {
+	s := value
+	var negative bool
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var res time.Duration
+	for len(s) > 0 {
+		numberLength := strings.IndexFunc(s, func(r rune) bool {
+			return r != '.' && (r < '0' || r > '9')
+		})
+		if numberLength < 0 {
+			numberLength = len(s)
+		}
+		if numberLength == 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number := s[:numberLength]
+		s = s[numberLength:]
+		unitLength := strings.IndexFunc(s, func(r rune) bool {
+			return r == '.' || (r >= '0' && r <= '9')
+		})
+		if unitLength < 0 {
+			unitLength = len(s)
+		}
+		if unitLength == 0 {
+			return 0, fmt.Errorf("missing unit in duration %q", value)
+		}
+		unit := s[:unitLength]
+		s = s[unitLength:]
+
+		var item time.Duration
+		switch unit {
+		case "d", "w":
+			scale := 24 * time.Hour
+			if unit == "w" {
+				scale *= 7
+			}
+			var err error
+			item, err = scaleDuration(number, scale)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %s", value, err)
+			}
+		default:
+			var err error
+			item, err = time.ParseDuration(number + unit)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+		}
+		if res > math.MaxInt64-item {
+			return 0, fmt.Errorf("invalid duration %q: out of range", value)
+		}
+		res += item
+	}
+
+	if negative {
+		res = -res
+	}
+	return res, nil
+}
+
+func scaleDuration(number string, scale time.Duration) (time.Duration, error) {
+	if !strings.Contains(number, ".") {
+		value, err := strconv.ParseInt(number, 10, 64)
+		if err != nil || value > math.MaxInt64/int64(scale) {
+			return 0, fmt.Errorf("out of range")
+		}
+		return time.Duration(value) * scale, nil
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, err
+	}
+	res := value * float64(scale)
+	if res >= math.MaxInt64 {
+		return 0, fmt.Errorf("out of range")
+	}
+	return time.Duration(res), nil
+}
@@ -0,0 +1,75 @@
+package caddycfg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "0", want: 0},
+		{value: "30s", want: 30 * time.Second},
+		{value: "1h30m", want: 90 * time.Minute},
+		{value: "1.5h", want: 90 * time.Minute},
+		{value: "2d", want: 48 * time.Hour},
+		{value: "1w2d12h", want: 9*24*time.Hour + 12*time.Hour},
+		{value: "0.5d", want: 12 * time.Hour},
+		{value: "-1d", want: -24 * time.Hour},
+		{value: "100µs", want: 100 * time.Microsecond},
+		{value: "", wantErr: true},
+		{value: "30", wantErr: true},
+		{value: "d", wantErr: true},
+		{value: "1y", wantErr: true},
+		{value: "1000000w", wantErr: true},
+		{value: "106751d24h", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseDuration(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	type config struct {
+		Timeout  time.Duration  `caddy:"timeout"`
+		Optional *time.Duration `caddy:"optional"`
+		Default  time.Duration  `caddy:"default,default=1w"`
+	}
+
+	week := 7 * 24 * time.Hour
+	halfHour := 30 * time.Minute
+
+	var dest config
+	c := caddy.NewTestController("http", `
+        root {
+            timeout 1d12h
+            optional 30m
+        }`)
+	require.NoError(t, Unmarshal(c, &dest))
+	require.Equal(t, config{Timeout: 36 * time.Hour, Optional: &halfHour, Default: week}, dest)
+
+	c = caddy.NewTestController("http", `
+        root {
+            timeout 12
+        }`)
+	require.EqualError(t, Unmarshal(c, &dest), `Testfile:3: missing unit in duration "12"`)
+
+	var durations []time.Duration
+	c = caddy.NewTestController("http", "root 1s 2m")
+	require.NoError(t, Unmarshal(c, &durations))
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Minute}, durations)
+}
@@ -0,0 +1,104 @@
+package caddycfg
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// processInterface decodes an entry into a generic tree just like encoding/json does for interface{} values:
+//   - an entry with no arguments and no block is nil
+//   - a single argument is a string
+//   - several arguments are []interface{} of strings
+//   - a block is map[string]interface{} of its entries keyed by their first tokens
+//   - arguments followed with a block are []interface{} of strings with the block map as the last item
+func (c *caddyCfgUnmarshaler) processInterface(s Stream, v reflect.Value) error {
+	if v.Type().NumMethod() > 0 {
+		return tokenErrorf(c.headToken, KindUnsupportedType, "unmarshal into %s is not supported", v.Type())
+	}
+
+	value, err := c.genericValue(s)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	v.Set(reflect.ValueOf(value))
+
+	return nil
+}
+
+func (c *caddyCfgUnmarshaler) genericValue(s Stream) (interface{}, error) {
+	var args []interface{}
+	for s.NextArg() {
+		t := s.Token()
+		s.Confirm()
+		if t.Value == "{" {
+			block, err := c.genericBlock(t, s)
+			if err != nil {
+				return nil, err
+			}
+			if len(args) == 0 {
+				return block, nil
+			}
+			return append(args, block), nil
+		}
+		args = append(args, t.Value)
+	}
+
+	switch len(args) {
+	case 0:
+		return nil, nil
+	case 1:
+		return args[0], nil
+	default:
+		return args, nil
+	}
+}
+
+func (c *caddyCfgUnmarshaler) genericBlock(openToken Token, s Stream) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	keysTaken := map[string]Token{}
+	depth := blockDepth(s)
+	for s.Next() {
+		t := s.Token()
+		s.Confirm()
+		if t.Value == "}" {
+			return res, nil
+		}
+
+		key := c.normalize(t.Value)
+		var err error
+		if t.Value == "{" {
+			err = tokenErrorf(t, KindUnexpectedBlock, "unexpected block, key expected")
+		} else if prevKeyToken, alreadyTaken := keysTaken[key]; alreadyTaken {
+			err = tokenErrorf(t, KindDuplicateKey,
+				"using key %s which has already been taken at %s:%d",
+				key,
+				prevKeyToken.File,
+				prevKeyToken.Lin,
+			)
+		}
+		if err != nil {
+			if c.recoverFrom(s, t, err, depth) {
+				continue
+			}
+			return nil, err
+		}
+		keysTaken[key] = t
+
+		c.pushPath(fmt.Sprintf("[%#v]", key))
+		value, err := c.genericValue(s)
+		c.popPath()
+		if err != nil {
+			if c.recoverFrom(s, t, err, depth) {
+				continue
+			}
+			return nil, err
+		}
+		res[key] = value
+	}
+
+	return nil, tokenErrorf(openToken, KindUnclosedBlock, "} expected")
+}
@@ -0,0 +1,112 @@
+package caddycfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterface(t *testing.T) {
+	type (
+		config struct {
+			Any   interface{}            `json:"any"`
+			Extra map[string]interface{} `json:"extra"`
+		}
+		sample struct {
+			name     string
+			input    string
+			expected interface{}
+			errMsg   string
+		}
+	)
+
+	samples := []sample{
+		{
+			name:     "nil",
+			input:    "root {\n  any\n}",
+			expected: config{},
+		},
+		{
+			name:     "string",
+			input:    "root {\n  any value\n}",
+			expected: config{Any: "value"},
+		},
+		{
+			name:     "list",
+			input:    "root {\n  any a b c\n}",
+			expected: config{Any: []interface{}{"a", "b", "c"}},
+		},
+		{
+			name: "tree",
+			input: `root {
+                        any {
+                            name value
+                            flag
+                            list 1 2
+                            nested {
+                                a b
+                            }
+                            args x {
+                                y z
+                            }
+                        }
+                        extra {
+                            key value
+                        }
+                    }`,
+			expected: config{
+				Any: map[string]interface{}{
+					"name": "value",
+					"flag": nil,
+					"list": []interface{}{"1", "2"},
+					"nested": map[string]interface{}{
+						"a": "b",
+					},
+					"args": []interface{}{"x", map[string]interface{}{"y": "z"}},
+				},
+				Extra: map[string]interface{}{"key": "value"},
+			},
+		},
+		{
+			name:   "error-duplicate-key",
+			input:  "root {\n  any {\n    a 1\n    a 2\n  }\n}",
+			errMsg: "Caddyfile:4: using key a which has already been taken at Caddyfile:3",
+		},
+		{
+			name:   "error-anonymous-block",
+			input:  "root {\n  any {\n    {\n    }\n  }\n}",
+			errMsg: "Caddyfile:3: unexpected block, key expected",
+		},
+		{
+			name:   "error-unclosed",
+			input:  "root {\n  any {\n    a 1\n",
+			errMsg: "Caddyfile:2: } expected",
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			var dest config
+			err := UnmarshalString(s.input, &dest)
+			if len(s.errMsg) > 0 {
+				require.EqualError(t, err, s.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, s.expected, dest)
+
+			data, err := Marshal("root", dest)
+			require.NoError(t, err)
+			var restored config
+			require.NoError(t, UnmarshalString(string(data), &restored))
+			require.Equal(t, dest, restored)
+		})
+	}
+
+	var top interface{}
+	require.NoError(t, UnmarshalString("root a {\n  b c\n}", &top))
+	require.Equal(t, []interface{}{"a", map[string]interface{}{"b": "c"}}, top)
+
+	var stringer interface{ String() string }
+	require.EqualError(t, UnmarshalString("root a", &stringer), "Caddyfile:1: unmarshal into interface { String() string } is not supported")
+}
@@ -1,10 +1,61 @@
 package caddycfg
 
 import (
+	"errors"
+	"math"
 	"reflect"
 	"strconv"
 )
 
+// integerBase returns a base to parse integers with, 0 means Go integer literal syntax
+func (c *caddyCfgUnmarshaler) integerBase() int {
+	if c.allowIntegerLiterals {
+		return 0
+	}
+	return 10
+}
+
+// parseInt parses a signed integer of type typ having the given bit size, 0 means int
+func (c *caddyCfgUnmarshaler) parseInt(t Token, typ reflect.Type, bitSize int) (int64, error) {
+	var value int64
+	var err error
+	if bitSize == 0 && !c.allowIntegerLiterals {
+		var v int
+		v, err = strconv.Atoi(t.Value)
+		value = int64(v)
+	} else {
+		value, err = strconv.ParseInt(t.Value, c.integerBase(), bitSize)
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		if bitSize == 0 {
+			bitSize = strconv.IntSize
+		}
+		min := int64(math.MinInt64) >> (64 - bitSize)
+		max := int64(math.MaxInt64) >> (64 - bitSize)
+		return 0, tokenErrorf(t, KindInvalidValue, "unmarshal into %s: %s is out of range [%d, %d]", typ, t.Value, min, max)
+	}
+	if err != nil {
+		return 0, tokenErrorf(t, KindInvalidValue, "%s", err)
+	}
+	return value, nil
+}
+
+// parseUint parses an unsigned integer of type typ having the given bit size, 0 means uint
+func (c *caddyCfgUnmarshaler) parseUint(t Token, typ reflect.Type, bitSize int) (uint64, error) {
+	value, err := strconv.ParseUint(t.Value, c.integerBase(), bitSize)
+	if errors.Is(err, strconv.ErrRange) {
+		if bitSize == 0 {
+			bitSize = strconv.IntSize
+		}
+		max := uint64(math.MaxUint64) >> (64 - bitSize)
+		return 0, tokenErrorf(t, KindInvalidValue, "unmarshal into %s: %s is out of range [0, %d]", typ, t.Value, max)
+	}
+	if err != nil {
+		return 0, tokenErrorf(t, KindInvalidValue, "%s", err)
+	}
+	return value, nil
+}
+
 func (c *caddyCfgUnmarshaler) processInt8(s Stream, v reflect.Value) error {
 	if err := c.needArgValue(s, v); err != nil {
 		return err
@@ -12,9 +63,9 @@ func (c *caddyCfgUnmarshaler) processInt8(s Stream, v reflect.Value) error {
 
 	t := s.Token()
 	r := ref(v)
-	value, err := strconv.ParseInt(t.Value, 10, 8)
+	value, err := c.parseInt(t, r.Type(), 8)
 	if err != nil {
-		return TokenError(t, err)
+		return err
 	}
 	r.Set(reflect.ValueOf(int8(value)))
 
@@ -30,9 +81,9 @@ func (c *caddyCfgUnmarshaler) processInt16(s Stream, v reflect.Value) error {
 
 	t := s.Token()
 	r := ref(v)
-	value, err := strconv.ParseInt(t.Value, 10, 16)
+	value, err := c.parseInt(t, r.Type(), 16)
 	if err != nil {
-		return TokenError(t, err)
+		return err
 	}
 	r.Set(reflect.ValueOf(int16(value)))
 
@@ -48,9 +99,9 @@ func (c *caddyCfgUnmarshaler) processInt32(s Stream, v reflect.Value) error {
 
 	t := s.Token()
 	r := ref(v)
-	value, err := strconv.ParseInt(t.Value, 10, 32)
+	value, err := c.parseInt(t, r.Type(), 32)
 	if err != nil {
-		return TokenError(t, err)
+		return err
 	}
 	r.Set(reflect.ValueOf(int32(value)))
 
@@ -66,9 +117,9 @@ func (c *caddyCfgUnmarshaler) processInt64(s Stream, v reflect.Value) error {
 
 	t := s.Token()
 	r := ref(v)
-	value, err := strconv.ParseInt(t.Value, 10, 64)
+	value, err := c.parseInt(t, r.Type(), 64)
 	if err != nil {
-		return TokenError(t, err)
+		return err
 	}
 	r.Set(reflect.ValueOf(value))
 
@@ -84,11 +135,11 @@ func (c *caddyCfgUnmarshaler) processInt(s Stream, v reflect.Value) error {
 
 	t := s.Token()
 	r := ref(v)
-	value, err := strconv.Atoi(t.Value)
+	value, err := c.parseInt(t, r.Type(), 0)
 	if err != nil {
-		return TokenError(t, err)
+		return err
 	}
-	r.Set(reflect.ValueOf(value))
+	r.Set(reflect.ValueOf(int(value)))
 
 	s.Confirm()
 
@@ -102,9 +153,9 @@ func (c *caddyCfgUnmarshaler) processUint8(s Stream, v reflect.Value) error {
 
 	t := s.Token()
 	r := ref(v)
-	value, err := strconv.ParseUint(t.Value, 10, 8)
+	value, err := c.parseUint(t, r.Type(), 8)
 	if err != nil {
-		return TokenError(t, err)
+		return err
 	}
 	r.Set(reflect.ValueOf(uint8(value)))
 
@@ -120,9 +171,9 @@ func (c *caddyCfgUnmarshaler) processUint16(s Stream, v reflect.Value) error {
 
 	t := s.Token()
 	r := ref(v)
-	value, err := strconv.ParseUint(t.Value, 10, 16)
+	value, err := c.parseUint(t, r.Type(), 16)
 	if err != nil {
-		return TokenError(t, err)
+		return err
 	}
 	r.Set(reflect.ValueOf(uint16(value)))
 
@@ -138,9 +189,9 @@ func (c *caddyCfgUnmarshaler) processUint32(s Stream, v reflect.Value) error {
 
 	t := s.Token()
 	r := ref(v)
-	value, err := strconv.ParseUint(t.Value, 10, 32)
+	value, err := c.parseUint(t, r.Type(), 32)
 	if err != nil {
-		return TokenError(t, err)
+		return err
 	}
 	r.Set(reflect.ValueOf(uint32(value)))
 
@@ -156,9 +207,9 @@ func (c *caddyCfgUnmarshaler) processUint64(s Stream, v reflect.Value) error {
 
 	t := s.Token()
 	r := ref(v)
-	value, err := strconv.ParseUint(t.Value, 10, 64)
+	value, err := c.parseUint(t, r.Type(), 64)
 	if err != nil {
-		return TokenError(t, err)
+		return err
 	}
 	r.Set(reflect.ValueOf(value))
 
@@ -174,9 +225,9 @@ func (c *caddyCfgUnmarshaler) processUint(s Stream, v reflect.Value) error {
 
 	t := s.Token()
 	r := ref(v)
-	value, err := strconv.ParseUint(t.Value, 10, 64)
+	value, err := c.parseUint(t, r.Type(), 0)
 	if err != nil {
-		return TokenError(t, err)
+		return err
 	}
 	r.Set(reflect.ValueOf(uint(value)))
 
@@ -194,7 +245,7 @@ func (c *caddyCfgUnmarshaler) processFloat32(s Stream, v reflect.Value) error {
 	r := ref(v)
 	value, err := strconv.ParseFloat(t.Value, 32)
 	if err != nil {
-		return TokenError(t, err)
+		return tokenErrorf(t, KindInvalidValue, "%s", err)
 	}
 	r.Set(reflect.ValueOf(float32(value)))
 
@@ -212,7 +263,7 @@ func (c *caddyCfgUnmarshaler) processFloat64(s Stream, v reflect.Value) error {
 	r := ref(v)
 	value, err := strconv.ParseFloat(t.Value, 64)
 	if err != nil {
-		return TokenError(t, err)
+		return tokenErrorf(t, KindInvalidValue, "%s", err)
 	}
 	r.Set(reflect.ValueOf(value))
 
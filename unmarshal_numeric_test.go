@@ -689,3 +689,77 @@ func TestFloat64(t *testing.T) {
 		})
 	}
 }
+
+func TestIntegerLiterals(t *testing.T) {
+	type (
+		config struct {
+			Mode  uint32 `caddy:"mode"`
+			Mask  uint8  `caddy:"mask"`
+			Limit int    `caddy:"limit"`
+			Delta int8   `caddy:"delta"`
+		}
+		sample struct {
+			name     string
+			input    string
+			options  []Option
+			expected config
+			errMsg   string
+		}
+	)
+
+	literals := []Option{AllowIntegerLiterals(true)}
+	samples := []sample{
+		{
+			name:     "decimal",
+			input:    "root {\n  mode 0644\n  limit 1000000\n}",
+			expected: config{Mode: 644, Limit: 1000000},
+		},
+		{
+			name:     "literals",
+			input:    "root {\n  mode 0644\n  mask 0xff\n  limit 1_000_000\n  delta -0b101\n}",
+			options:  literals,
+			expected: config{Mode: 0644, Mask: 0xff, Limit: 1000000, Delta: -5},
+		},
+		{
+			name:   "error-literals-not-allowed",
+			input:  "root {\n  mask 0xff\n}",
+			errMsg: `Caddyfile:2: strconv.ParseUint: parsing "0xff": invalid syntax`,
+		},
+		{
+			name:   "error-separator-not-allowed",
+			input:  "root {\n  limit 1_000\n}",
+			errMsg: `Caddyfile:2: strconv.Atoi: parsing "1_000": invalid syntax`,
+		},
+		{
+			name:    "error-unsigned-overflow",
+			input:   "root {\n  mask 0x100\n}",
+			options: literals,
+			errMsg:  "Caddyfile:2: unmarshal into uint8: 0x100 is out of range [0, 255]",
+		},
+		{
+			name:   "error-signed-overflow",
+			input:  "root {\n  delta -129\n}",
+			errMsg: "Caddyfile:2: unmarshal into int8: -129 is out of range [-128, 127]",
+		},
+		{
+			name:   "error-int-overflow",
+			input:  "root {\n  limit 9223372036854775808\n}",
+			errMsg: "Caddyfile:2: unmarshal into int: 9223372036854775808 is out of range [-9223372036854775808, 9223372036854775807]",
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			tokens, err := tokenize("Caddyfile", []byte(s.input))
+			require.NoError(t, err)
+			var got config
+			err = NewTokensDecoder(tokens, s.options...).Decode(&got)
+			if len(s.errMsg) > 0 {
+				require.EqualError(t, err, s.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, s.expected, got)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package caddycfg
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate events rate: Count events per Interval. It is written as N/unit, where the unit is one of ms, s, m, h, d
+// and w (100/s, 5/m), or as N/duration (10/30s)
+type Rate struct {
+	Count    uint64
+	Interval time.Duration
+}
+
+func init() {
+	RegisterDecoder(reflect.TypeOf(Rate{}), func(t Token) (interface{}, error) {
+		return parseRate(t.Value)
+	})
+}
+
+var rateUnits = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+}
+
+// PerSecond returns the number of events per second
+func (r Rate) PerSecond() float64 {
+	if r.Interval <= 0 {
+		return 0
+	}
+	return float64(r.Count) / r.Interval.Seconds()
+}
+
+// String ...
+func (r Rate) String() string {
+	count := strconv.FormatUint(r.Count, 10)
+	for _, unit := range rateUnits {
+		if r.Interval == unit.duration {
+			return count + "/" + unit.name
+		}
+	}
+	return count + "/" + r.Interval.String()
+}
+
+func parseRate(value string) (Rate, error) {
+	pos := strings.IndexByte(value, '/')
+	if pos < 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q, N/unit expected", value)
+	}
+	count, err := strconv.ParseUint(value[:pos], 10, 64)
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid rate %q: %s", value, err)
+	}
+	interval, err := parseRateInterval(value[pos+1:])
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid rate %q: %s", value, err)
+	}
+	return Rate{
+		Count:    count,
+		Interval: interval,
+	}, nil
+}
+
+// parseRateInterval parses either a unit or a duration
+func parseRateInterval(value string) (time.Duration, error) {
+	for _, unit := range rateUnits {
+		if value == unit.name {
+			return unit.duration, nil
+		}
+	}
+	interval, err := parseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("interval must be positive")
+	}
+	return interval, nil
+}
+
+// processRateField decodes numeric value with rate tag option set as the number of events per the option unit.
+// Plain numbers are taken as they are
+func (c *caddyCfgUnmarshaler) processRateField(s Stream, v reflect.Value) error {
+	if err := c.needArgValue(s, v); err != nil {
+		return err
+	}
+
+	t := s.Token()
+	unitName := c.tag.options["rate"]
+	if unitName == "" {
+		unitName = "s"
+	}
+	unit, err := parseRateInterval(unitName)
+	if err != nil {
+		rt, _ := refType(v.Type())
+		return tokenErrorf(t, KindUnsupportedType, "unmarshal into %s: invalid rate tag option: %s", rt, err)
+	}
+
+	var value *big.Rat
+	if strings.IndexByte(t.Value, '/') < 0 {
+		var ok bool
+		isNumber := strings.Trim(t.Value, "0123456789.") == ""
+		if value, ok = new(big.Rat).SetString(t.Value); !isNumber || !ok {
+			return tokenErrorf(t, KindInvalidValue, "invalid rate %q", t.Value)
+		}
+	} else {
+		rate, err := parseRate(t.Value)
+		if err != nil {
+			return tokenErrorf(t, KindInvalidValue, "%s", err)
+		}
+		value = new(big.Rat).SetFrac(
+			new(big.Int).Mul(new(big.Int).SetUint64(rate.Count), big.NewInt(int64(unit))),
+			big.NewInt(int64(rate.Interval)),
+		)
+	}
+	if err := setNumber(ref(v), t, value); err != nil {
+		return err
+	}
+
+	s.Confirm()
+
+	return nil
+}
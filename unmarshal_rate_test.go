@@ -0,0 +1,103 @@
+package caddycfg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Rate
+		wantErr bool
+	}{
+		{value: "100/s", want: Rate{Count: 100, Interval: time.Second}},
+		{value: "5/m", want: Rate{Count: 5, Interval: time.Minute}},
+		{value: "1000/d", want: Rate{Count: 1000, Interval: 24 * time.Hour}},
+		{value: "10/30s", want: Rate{Count: 10, Interval: 30 * time.Second}},
+		{value: "1/1h30m", want: Rate{Count: 1, Interval: 90 * time.Minute}},
+		{value: "100", wantErr: true},
+		{value: "/s", wantErr: true},
+		{value: "-1/s", wantErr: true},
+		{value: "1/y", wantErr: true},
+		{value: "1/0s", wantErr: true},
+		{value: "1/-1s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseRate(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRate(t *testing.T) {
+	type config struct {
+		Limit     Rate    `caddy:"limit"`
+		Burst     *Rate   `caddy:"burst"`
+		PerSecond int     `caddy:"per_second,rate"`
+		PerMinute uint32  `caddy:"per_minute,rate=m"`
+		Average   float64 `caddy:"average,rate=s"`
+	}
+
+	burst := Rate{Count: 10, Interval: 30 * time.Second}
+	var dest config
+	c := caddy.NewTestController("http", `
+        root {
+            limit 100/s
+            burst 10/30s
+            per_second 6000/m
+            per_minute 50
+            average 1/4s
+        }`)
+	require.NoError(t, Unmarshal(c, &dest))
+	require.Equal(t, config{
+		Limit:     Rate{Count: 100, Interval: time.Second},
+		Burst:     &burst,
+		PerSecond: 100,
+		PerMinute: 50,
+		Average:   0.25,
+	}, dest)
+	require.Equal(t, float64(100), dest.Limit.PerSecond())
+	require.Equal(t, "10/30s", dest.Burst.String())
+
+	data, err := Marshal("root", dest)
+	require.NoError(t, err)
+	var restored config
+	require.NoError(t, UnmarshalString(string(data), &restored))
+	require.Equal(t, dest, restored)
+
+	errSamples := []struct {
+		input  string
+		errMsg string
+	}{
+		{
+			input:  "root {\n  per_second 1/m\n}",
+			errMsg: "Testfile:2: 1/m is not a whole number for int",
+		},
+		{
+			input:  "root {\n  per_minute 100000000/s\n}",
+			errMsg: "Testfile:2: 100000000/s overflows uint32",
+		},
+		{
+			input:  "root {\n  limit 100\n}",
+			errMsg: `Testfile:2: cannot unmarshal into caddycfg.Rate: invalid rate "100", N/unit expected`,
+		},
+	}
+	for _, s := range errSamples {
+		t.Run(s.errMsg, func(t *testing.T) {
+			var dest config
+			err := Unmarshal(caddy.NewTestController("http", s.input), &dest)
+			require.EqualError(t, err, s.errMsg)
+			require.ErrorIs(t, err, ErrInvalidValue)
+		})
+	}
+}
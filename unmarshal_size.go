@@ -0,0 +1,145 @@
+package caddycfg
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ByteSize size in bytes, written with SI (kB, MB, …) or IEC (KiB, MiB, …) suffixes, e.g. 10MB or 512KiB.
+// Single letter suffixes (K, M, …) are SI ones, units are case insensitive. Plain numbers are sizes in bytes
+type ByteSize uint64
+
+func init() {
+	RegisterDecoder(reflect.TypeOf(ByteSize(0)), func(t Token) (interface{}, error) {
+		size, err := parseByteSize(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !size.IsUint64() {
+			return nil, fmt.Errorf("size %s overflows %s", t.Value, reflect.TypeOf(ByteSize(0)))
+		}
+		return ByteSize(size.Uint64()), nil
+	})
+}
+
+type byteUnit struct {
+	name string
+	size uint64
+}
+
+// byteUnits units in order of String preference
+var byteUnits = []byteUnit{
+	{"EiB", 1 << 60}, {"PiB", 1 << 50}, {"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"EB", 1e18}, {"PB", 1e15}, {"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"kB", 1e3},
+	{"E", 1e18}, {"P", 1e15}, {"T", 1e12}, {"G", 1e9}, {"M", 1e6}, {"K", 1e3},
+	{"B", 1},
+}
+
+// String returns size with the largest unit it is a whole number of
+func (s ByteSize) String() string {
+	if s == 0 {
+		return "0"
+	}
+	for _, unit := range byteUnits {
+		if uint64(s)%unit.size == 0 {
+			return strconv.FormatUint(uint64(s)/unit.size, 10) + unit.name
+		}
+	}
+	return strconv.FormatUint(uint64(s), 10)
+}
+
+// parseByteSize parses size with an optional unit suffix, fractions of bytes are dropped
+func parseByteSize(value string) (*big.Int, error) {
+	numberLength := strings.IndexFunc(value, func(r rune) bool {
+		return r != '.' && (r < '0' || r > '9')
+	})
+	if numberLength < 0 {
+		numberLength = len(value)
+	}
+	number, unitName := value[:numberLength], value[numberLength:]
+
+	multiplier := uint64(1)
+	if unitName != "" {
+		multiplier = 0
+		for _, unit := range byteUnits {
+			if strings.EqualFold(unit.name, unitName) {
+				multiplier = unit.size
+				break
+			}
+		}
+	}
+	size, ok := new(big.Rat).SetString(number)
+	if number == "" || multiplier == 0 || !ok {
+		return nil, fmt.Errorf("invalid size %q", value)
+	}
+	size.Mul(size, new(big.Rat).SetInt(new(big.Int).SetUint64(multiplier)))
+	return new(big.Int).Quo(size.Num(), size.Denom()), nil
+}
+
+// processByteSizeField decodes numeric value with bytes tag option set as a byte size
+func (c *caddyCfgUnmarshaler) processByteSizeField(s Stream, v reflect.Value) error {
+	if err := c.needArgValue(s, v); err != nil {
+		return err
+	}
+
+	t := s.Token()
+	size, err := parseByteSize(t.Value)
+	if err != nil {
+		return tokenErrorf(t, KindInvalidValue, "%s", err)
+	}
+	if err := setNumber(ref(v), t, new(big.Rat).SetInt(size)); err != nil {
+		return err
+	}
+
+	s.Confirm()
+
+	return nil
+}
+
+// isNumericKind checks if values of kind k are numbers
+func isNumericKind(k reflect.Kind) bool {
+	switch k {
+	case
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// setNumber sets numeric value r with number n parsed from the token, n must be a whole number for integer values
+// and fit into r type
+func setNumber(r reflect.Value, t Token, n *big.Rat) error {
+	switch r.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f, _ := n.Float64()
+		if r.OverflowFloat(f) {
+			return tokenErrorf(t, KindInvalidValue, "%s overflows %s", t.Value, r.Type())
+		}
+		r.SetFloat(f)
+		return nil
+	}
+
+	if !n.IsInt() {
+		return tokenErrorf(t, KindInvalidValue, "%s is not a whole number for %s", t.Value, r.Type())
+	}
+	i := n.Num()
+	switch r.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		if !i.IsInt64() || r.OverflowInt(i.Int64()) {
+			return tokenErrorf(t, KindInvalidValue, "%s overflows %s", t.Value, r.Type())
+		}
+		r.SetInt(i.Int64())
+	default:
+		if !i.IsUint64() || r.OverflowUint(i.Uint64()) {
+			return tokenErrorf(t, KindInvalidValue, "%s overflows %s", t.Value, r.Type())
+		}
+		r.SetUint(i.Uint64())
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package caddycfg
+
+import (
+	"testing"
+
+	"github.com/caddyserver/caddy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    uint64
+		wantErr bool
+	}{
+		{value: "0", want: 0},
+		{value: "512", want: 512},
+		{value: "100B", want: 100},
+		{value: "10MB", want: 10000000},
+		{value: "10mb", want: 10000000},
+		{value: "1.5kB", want: 1500},
+		{value: "512KiB", want: 512 * 1024},
+		{value: "1.5GiB", want: 3 << 29},
+		{value: "2K", want: 2000},
+		{value: "0.1B", want: 0},
+		{value: "16EiB", want: 0, wantErr: false},
+		{value: "", wantErr: true},
+		{value: "MB", wantErr: true},
+		{value: "10XB", wantErr: true},
+		{value: "1.2.3MB", wantErr: true},
+		{value: "-1MB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseByteSize(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.value == "16EiB" {
+				// does not fit into 64 bits
+				require.False(t, got.IsUint64())
+				return
+			}
+			require.Equal(t, tt.want, got.Uint64())
+		})
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	type config struct {
+		Limit   ByteSize   `caddy:"limit"`
+		Buffers []ByteSize `caddy:"buffers"`
+		Body    int64      `caddy:"body,bytes"`
+		Small   uint16     `caddy:"small,bytes"`
+		Ratio   float64    `caddy:"ratio,bytes"`
+		Default int        `caddy:"default,bytes,default=1MiB"`
+	}
+
+	var dest config
+	c := caddy.NewTestController("http", `
+        root {
+            limit 10MB
+            buffers 4KiB 1.5MiB 100
+            body 2GiB
+            small 64KB
+            ratio 1.5kB
+        }`)
+	require.NoError(t, Unmarshal(c, &dest))
+	require.Equal(t, config{
+		Limit:   10000000,
+		Buffers: []ByteSize{4096, 3 << 19, 100},
+		Body:    2 << 30,
+		Small:   64000,
+		Ratio:   1500,
+		Default: 1 << 20,
+	}, dest)
+
+	data, err := Marshal("root", dest)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "limit 10MB\n")
+	require.Contains(t, string(data), "buffers 4KiB 1536KiB 100B\n")
+	var restored config
+	require.NoError(t, UnmarshalString(string(data), &restored))
+	require.Equal(t, dest, restored)
+
+	errSamples := []struct {
+		input  string
+		errMsg string
+	}{
+		{
+			input:  "root {\n  small 64KiB\n}",
+			errMsg: "Testfile:2: 64KiB overflows uint16",
+		},
+		{
+			input:  "root {\n  limit 16EiB\n}",
+			errMsg: "Testfile:2: cannot unmarshal into caddycfg.ByteSize: size 16EiB overflows caddycfg.ByteSize",
+		},
+		{
+			input:  "root {\n  body ten\n}",
+			errMsg: `Testfile:2: invalid size "ten"`,
+		},
+	}
+	for _, s := range errSamples {
+		t.Run(s.errMsg, func(t *testing.T) {
+			var dest config
+			err := Unmarshal(caddy.NewTestController("http", s.input), &dest)
+			require.EqualError(t, err, s.errMsg)
+			require.ErrorIs(t, err, ErrInvalidValue)
+		})
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
+	"net"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/caddyserver/caddy"
 	"github.com/stretchr/testify/assert"
@@ -54,6 +58,15 @@ func TestJSONUnmarshaler(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:   "success-need-escaping",
+			input:  `root "abc\"def\\gh"`,
+			target: &dest2,
+			expected: jsonUnmarshalerNeedEncoding{
+				a: `abc"def\\gh`,
+			},
+			wantErr: false,
+		},
 		{
 			name:    "error-junk-data",
 			input:   "root abcdefgh junk",
@@ -88,6 +101,106 @@ func TestJSONUnmarshaler(t *testing.T) {
 	}
 }
 
+type textUnmarshalerImpl struct {
+	a string
+}
+
+func (v *textUnmarshalerImpl) UnmarshalText(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("empty value")
+	}
+	v.a = string(data)
+	return nil
+}
+
+// UnmarshalJSON must not be used as encoding.TextUnmarshaler takes precedence
+func (v *textUnmarshalerImpl) UnmarshalJSON(data []byte) error {
+	return errors.New("json must not be used")
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	type (
+		config struct {
+			Value   textUnmarshalerImpl  `caddy:"value"`
+			Pointer *textUnmarshalerImpl `caddy:"pointer"`
+			IP      net.IP               `caddy:"ip"`
+			Number  **big.Int            `caddy:"number"`
+			List    []net.IP             `caddy:"list"`
+		}
+		sample struct {
+			name     string
+			input    string
+			expected config
+			wantErr  bool
+		}
+	)
+
+	number := big.NewInt(12345678901234567)
+	samples := []sample{
+		{
+			name: "success",
+			input: `
+                root {
+                    value a
+                    pointer b
+                    ip 127.0.0.1
+                    number 12345678901234567
+                    list ::1 10.0.0.1
+                }`,
+			expected: config{
+				Value:   textUnmarshalerImpl{a: "a"},
+				Pointer: &textUnmarshalerImpl{a: "b"},
+				IP:      net.ParseIP("127.0.0.1"),
+				Number:  &number,
+				List:    []net.IP{net.ParseIP("::1"), net.ParseIP("10.0.0.1")},
+			},
+		},
+		{
+			name: "error-invalid-value",
+			input: `
+                root {
+                    ip 127.0.0
+                }`,
+			wantErr: true,
+		},
+		{
+			name: "error-unmarshaler-failed",
+			input: `
+                root {
+                    value ""
+                }`,
+			wantErr: true,
+		},
+		{
+			name: "error-missing-data",
+			input: `
+                root {
+                    pointer
+                }`,
+			wantErr: true,
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			c := caddy.NewTestController("http", s.input)
+			var dest config
+			err := Unmarshal(c, &dest)
+			if err != nil {
+				if !s.wantErr {
+					t.Error(err)
+				}
+				return
+			}
+			if err == nil && s.wantErr {
+				t.Errorf("error expected")
+				return
+			}
+			require.Equal(t, s.expected, dest)
+		})
+	}
+}
+
 func reduceToValue(e interface{}) interface{} {
 	v := reflect.ValueOf(e)
 	for v.Type().Kind() == reflect.Ptr {
@@ -100,6 +213,7 @@ func TestBoolean(t *testing.T) {
 	type sample struct {
 		name     string
 		input    string
+		options  []Option
 		target   *bool
 		expected bool
 		wantErr  bool
@@ -128,9 +242,32 @@ func TestBoolean(t *testing.T) {
 			expected: false,
 			wantErr:  true,
 		},
+		{
+			name:     "flag",
+			input:    "root",
+			target:   &target,
+			expected: true,
+			wantErr:  false,
+		},
 		{
 			name:     "error-missing-data",
 			input:    "root",
+			options:  []Option{RequireBoolValues(true)},
+			target:   &target,
+			expected: false,
+			wantErr:  true,
+		},
+		{
+			name:     "alias",
+			input:    "root off",
+			options:  []Option{AllowBoolAliases(true)},
+			target:   &target,
+			expected: false,
+			wantErr:  false,
+		},
+		{
+			name:     "error-alias-not-allowed",
+			input:    "root yes",
 			target:   &target,
 			expected: false,
 			wantErr:  true,
@@ -147,7 +284,7 @@ func TestBoolean(t *testing.T) {
 	for _, s := range samples {
 		t.Run(s.name, func(t *testing.T) {
 			c := caddy.NewTestController("http", s.input)
-			err := Unmarshal(c, s.target)
+			err := NewDecoder(c, s.options...).Decode(s.target)
 			if err != nil {
 				if !s.wantErr {
 					t.Error(err)
@@ -890,3 +1027,734 @@ func TestRegression(t *testing.T) {
 	}
 	require.Equal(t, betweenType{open: "a", close: "b"}, b)
 }
+
+func TestRequiredFields(t *testing.T) {
+	type (
+		sub struct {
+			C int `caddy:"c,required"`
+		}
+		embedded struct {
+			B int `caddy:"b,required"`
+		}
+		config struct {
+			embedded
+			A   int  `caddy:"a,required"`
+			D   int  `caddy:"d,optional"`
+			E   int  `caddy:"e"`
+			Sub *sub `caddy:"sub"`
+		}
+		sample struct {
+			name     string
+			input    string
+			options  []Option
+			expected config
+			errMsg   string
+		}
+	)
+
+	samples := []sample{
+		{
+			name: "success",
+			input: `
+                root {
+                    a 1
+                    b 2
+                }`,
+			expected: config{embedded: embedded{B: 2}, A: 1},
+		},
+		{
+			name: "error-missing-keys",
+			input: `root {
+                        e 1
+                    }`,
+			errMsg: "Testfile:1: unmarshal into caddycfg.config: missing required keys 'b' (embedded.B), 'a' (A)",
+		},
+		{
+			name: "error-missing-sub-key",
+			input: `root {
+                        a 1
+                        b 2
+                        sub {
+                        }
+                    }`,
+			errMsg: "Testfile:4: unmarshal into caddycfg.sub: missing required key 'c' (C)",
+		},
+		{
+			name: "error-all-fields-required",
+			input: `root {
+                        a 1
+                        b 2
+                    }`,
+			options: []Option{RequireAllFields(true)},
+			errMsg:  "Testfile:1: unmarshal into caddycfg.config: missing required keys 'e' (E), 'sub' (Sub)",
+		},
+		{
+			name: "success-all-fields-required",
+			input: `root {
+                        a 1
+                        b 2
+                        e 3
+                        sub {
+                            c 4
+                        }
+                    }`,
+			options:  []Option{RequireAllFields(true)},
+			expected: config{embedded: embedded{B: 2}, A: 1, E: 3, Sub: &sub{C: 4}},
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			c := caddy.NewTestController("http", s.input)
+			var dest config
+			err := NewDecoder(c, s.options...).Decode(&dest)
+			if len(s.errMsg) > 0 {
+				require.EqualError(t, err, s.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, s.expected, dest)
+		})
+	}
+}
+
+func TestDefaultValues(t *testing.T) {
+	type (
+		sub struct {
+			A int `caddy:"a,default=7"`
+		}
+		config struct {
+			Name  string   `caddy:"name,default=unnamed"`
+			Port  uint16   `caddy:"port,required,default=8080"`
+			Hosts []string `caddy:"hosts,default=a b"`
+			JSON  tmpType  `caddy:"json,default=raw"`
+			Sub   sub      `caddy:"sub"`
+		}
+		invalidDefault struct {
+			A int `caddy:"a,default=value"`
+		}
+		sample struct {
+			name     string
+			input    string
+			target   interface{}
+			expected interface{}
+			errMsg   string
+		}
+	)
+
+	samples := []sample{
+		{
+			name: "success-defaults",
+			input: `root {
+                    }`,
+			target: &config{},
+			expected: &config{
+				Name:  "unnamed",
+				Port:  8080,
+				Hosts: []string{"a", "b"},
+				JSON:  tmpType{a: "raw"},
+			},
+		},
+		{
+			name: "success-overridden",
+			input: `root {
+                        name named
+                        port 80
+                        sub {
+                        }
+                    }`,
+			target: &config{},
+			expected: &config{
+				Name:  "named",
+				Port:  80,
+				Hosts: []string{"a", "b"},
+				JSON:  tmpType{a: "raw"},
+				Sub:   sub{A: 7},
+			},
+		},
+		{
+			name: "error-invalid-default",
+			input: `root {
+                    }`,
+			target: &invalidDefault{},
+			errMsg: `Testfile:1: unmarshal into int: invalid default value of key 'a': strconv.Atoi: parsing "value": invalid syntax`,
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			c := caddy.NewTestController("http", s.input)
+			err := Unmarshal(c, s.target)
+			if len(s.errMsg) > 0 {
+				require.EqualError(t, err, s.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, s.expected, s.target)
+		})
+	}
+}
+
+func TestUnmarshalString(t *testing.T) {
+	type (
+		sub struct {
+			Args
+			A int `json:"a"`
+		}
+		config struct {
+			Name  string            `json:"name"`
+			Items []string          `json:"items"`
+			Subs  []sub             `json:"subs"`
+			Map   map[string][]int  `json:"map"`
+			Text  map[string]string `json:"text"`
+		}
+		sample struct {
+			name   string
+			input  string
+			errMsg string
+		}
+	)
+
+	samples := []sample{
+		{
+			name: "success",
+			input: `root {
+                        # comment
+                        name "quoted name"
+                        items a b   c
+                        subs {
+                            x y {
+                                a 1
+                            }
+                            z {
+                            }
+                        }
+                        map {
+                            a 1 2
+                            b
+                        }
+                        text {
+                            a "multiline
+value" 
+                            b "escaped \"quote\""
+                        }
+                    }`,
+		},
+		{
+			name:   "error-trailing-data",
+			input:  "root {\n}\nother",
+			errMsg: "Caddyfile:3: got unexpected data 'other' for plugin 'root'",
+		},
+		{
+			name:   "error-unclosed-block",
+			input:  "root {\n  name a",
+			errMsg: "Caddyfile:2: unmarshal into caddycfg.config: { expected",
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			var got config
+			err := UnmarshalString(s.input, &got)
+			if len(s.errMsg) > 0 {
+				require.EqualError(t, err, s.errMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			// the result must be the same as for caddy.Controller
+			var expected config
+			if err := Unmarshal(caddy.NewTestController("http", s.input), &expected); err != nil {
+				t.Fatal(err)
+			}
+			require.Equal(t, expected, got)
+		})
+	}
+}
+
+func TestUnmarshalReader(t *testing.T) {
+	var dest []int
+	require.NoError(t, UnmarshalReader("Caddyfile", strings.NewReader("root 1 \\\n 2 3"), &dest))
+	require.Equal(t, []int{1, 2, 3}, dest)
+
+	err := UnmarshalReader("plugin.conf", strings.NewReader(`root "1`), &dest)
+	require.EqualError(t, err, "plugin.conf:1: unterminated quoted token")
+}
+
+func TestUnmarshalTokens(t *testing.T) {
+	tokens := []Token{
+		{File: "a", Value: "root", Lin: 1},
+		{File: "a", Value: "1", Lin: 1},
+		{File: "a", Value: "2", Lin: 2},
+	}
+	var dest []int
+	err := UnmarshalTokens(tokens, &dest)
+	require.EqualError(t, err, "a:2: got unexpected data '2' for plugin 'root'")
+
+	dest = nil
+	require.NoError(t, UnmarshalTokens(tokens[:2], &dest))
+	require.Equal(t, []int{1}, dest)
+}
+
+func TestArrays(t *testing.T) {
+	type (
+		pair   [2]string
+		config struct {
+			Pair    pair              `json:"pair"`
+			Point   *[3]int           `json:"point"`
+			Corners [2]map[string]int `json:"corners"`
+		}
+		sample struct {
+			name     string
+			input    string
+			expected config
+			errMsg   string
+		}
+	)
+
+	point := [3]int{1, 2, 3}
+	samples := []sample{
+		{
+			name: "success",
+			input: `root {
+                        pair a b
+                        point 1 2 3
+                        corners {
+                            {
+                                x 0
+                            }
+                            {
+                                x 10
+                            }
+                        }
+                    }`,
+			expected: config{
+				Pair:    pair{"a", "b"},
+				Point:   &point,
+				Corners: [2]map[string]int{{"x": 0}, {"x": 10}},
+			},
+		},
+		{
+			name:   "error-too-many",
+			input:  "root {\n  pair a b c\n}",
+			errMsg: "Caddyfile:2: unmarshal into caddycfg.pair: expected 2 values, got 3",
+		},
+		{
+			name:   "error-too-few",
+			input:  "root {\n  point 1 2\n}",
+			errMsg: "Caddyfile:2: unmarshal into [3]int: expected 3 values, got 2",
+		},
+		{
+			name:   "error-too-many-blocked",
+			input:  "root {\n  corners {\n    {\n    }\n    {\n    }\n    {\n    }\n  }\n}",
+			errMsg: "Caddyfile:7: unmarshal into [2]map[string]int: expected 2 values, got 3",
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			var dest config
+			err := UnmarshalString(s.input, &dest)
+			if len(s.errMsg) > 0 {
+				require.EqualError(t, err, s.errMsg)
+				require.ErrorIs(t, err, ErrInvalidValue)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, s.expected, dest)
+
+			data, err := Marshal("root", dest)
+			require.NoError(t, err)
+			var restored config
+			require.NoError(t, UnmarshalString(string(data), &restored))
+			require.Equal(t, dest, restored)
+		})
+	}
+}
+
+func TestPointerContainers(t *testing.T) {
+	type config struct {
+		List   *[]int                `json:"list"`
+		Map    *map[string]int       `json:"map"`
+		Nested **[]string            `json:"nested"`
+		Blocks *[]map[string]string  `json:"blocks"`
+		Keyed  *map[int]*[]time.Time `json:"keyed"`
+	}
+
+	var dest config
+	require.NoError(t, UnmarshalString(`root {
+    list 1 2
+    map {
+        a 1
+    }
+    nested a b
+    blocks {
+        {
+            k v
+        }
+    }
+}`, &dest))
+	require.Equal(t, []int{1, 2}, *dest.List)
+	require.Equal(t, map[string]int{"a": 1}, *dest.Map)
+	require.Equal(t, []string{"a", "b"}, **dest.Nested)
+	require.Equal(t, []map[string]string{{"k": "v"}}, *dest.Blocks)
+	require.Nil(t, dest.Keyed)
+}
+
+func TestRepeatedKeys(t *testing.T) {
+	type (
+		upstream struct {
+			Args
+			Weight int `caddy:"weight,omitempty"`
+		}
+		config struct {
+			Allow     []string   `caddy:"allow"`
+			Deny      *[]string  `caddy:"deny"`
+			Upstreams []upstream `caddy:"upstream,repeat"`
+			Headers   [][]string `caddy:"header,repeat"`
+			Name      string     `caddy:"name"`
+		}
+		invalid struct {
+			Name string `caddy:"name,repeat"`
+		}
+	)
+
+	var dest config
+	err := UnmarshalString(`root {
+    allow a b
+    upstream host1 {
+        weight 2
+    }
+    allow c
+    header X-A a
+    deny d
+    upstream host2 {
+    }
+    header X-B b
+    deny e f
+}`, &dest)
+	require.NoError(t, err)
+	deny := []string{"d", "e", "f"}
+	require.Equal(t, config{
+		Allow: []string{"a", "b", "c"},
+		Deny:  &deny,
+		Upstreams: []upstream{
+			{Args: Args{data: []string{"host1"}}, Weight: 2},
+			{Args: Args{data: []string{"host2"}}},
+		},
+		Headers: [][]string{{"X-A", "a"}, {"X-B", "b"}},
+	}, dest)
+
+	data, err := Marshal("root", dest)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "\tupstream host1 {\n\t\tweight 2\n\t}\n\tupstream host2 {\n\t}\n")
+	var restored config
+	require.NoError(t, UnmarshalString(string(data), &restored))
+	require.Equal(t, dest, restored)
+
+	err = UnmarshalString("root {\n  name a\n  name b\n}", &dest)
+	require.EqualError(t, err, "Caddyfile:3: unmarshal into caddycfg.config: duplicate key name, it has already been set at Caddyfile:2")
+	require.ErrorIs(t, err, ErrDuplicateKey)
+
+	err = UnmarshalString("root {\n  upstream host {\n    weight x\n  }\n}", &dest)
+	var te *TokenError
+	require.True(t, errors.As(err, &te))
+	require.Equal(t, "Upstreams[0].Weight", te.Path)
+
+	var inv invalid
+	err = UnmarshalString("root {\n  name a\n}", &inv)
+	require.EqualError(t, err, "Caddyfile:2: unmarshal into string: repeat tag option is only supported for slices")
+
+	// every occurrence of a repeated key is a single item
+	var single struct {
+		R []string `caddy:"r,repeat"`
+		B bool     `caddy:"b"`
+	}
+	err = UnmarshalString("root {\n  r a b\n}", &single)
+	require.EqualError(t, err, "Caddyfile:2: unmarshal into []string: repeated key r takes a single item, got extra b")
+	require.True(t, errors.Is(err, ErrUnexpectedData))
+	require.True(t, errors.As(err, &te))
+	require.Equal(t, "b", te.Value)
+	require.Equal(t, 7, te.Col)
+}
+
+type headedRoute struct {
+	Args
+	Upstream string `caddy:"upstream"`
+}
+
+func (r headedRoute) Err(head Token) error {
+	if head.Value != "route" {
+		return TokenErrorf(head, "route expected, got %s", head.Value)
+	}
+	if r.Head() != head.Value {
+		return TokenErrorf(head, "the head is not kept")
+	}
+	if len(r.Arguments()) != 1 {
+		return TokenErrorf(head, "route path expected")
+	}
+	return nil
+}
+
+type headedMatcher struct {
+	head string
+	args []string
+}
+
+func (m *headedMatcher) ConsumeArguments(head Token, args []Token) error {
+	m.head = head.Value
+	for _, arg := range args {
+		m.args = append(m.args, arg.Value)
+	}
+	return nil
+}
+
+func (m *headedMatcher) Arguments() []string {
+	return m.args
+}
+
+func TestHeadedSliceItems(t *testing.T) {
+	type config struct {
+		Routes   []headedRoute    `caddy:"routes,heads"`
+		Matchers []*headedMatcher `caddy:"matchers,heads"`
+	}
+
+	var dest config
+	err := UnmarshalString(`root {
+    routes {
+        route /api {
+            upstream localhost:8080
+        }
+        route /static {
+        }
+    }
+    matchers {
+        path /a /b
+        header X-Debug {
+        }
+    }
+}`, &dest)
+	require.NoError(t, err)
+	require.Equal(t, config{
+		Routes: []headedRoute{
+			{Args: Args{head: "route", data: []string{"/api"}}, Upstream: "localhost:8080"},
+			{Args: Args{head: "route", data: []string{"/static"}}},
+		},
+		Matchers: []*headedMatcher{
+			{head: "path", args: []string{"/a", "/b"}},
+			{head: "header", args: []string{"X-Debug"}},
+		},
+	}, dest)
+
+	err = UnmarshalString("root {\n  routes {\n    location /api {\n    }\n  }\n}", &dest)
+	require.EqualError(t, err, "Caddyfile:3: route expected, got location")
+
+	err = UnmarshalString("root {\n  routes {\n    route {\n    }\n  }\n}", &dest)
+	require.EqualError(t, err, "Caddyfile:3: route path expected")
+}
+
+func TestSliceItemArguments(t *testing.T) {
+	type item struct {
+		Args
+		X int `caddy:"x"`
+	}
+
+	// items of slices without heads option take all tokens of a line as arguments
+	var dest struct {
+		Items []item `caddy:"items"`
+	}
+	require.NoError(t, UnmarshalString("root {\n  items {\n    a b {\n      x 1\n    }\n  }\n}", &dest))
+	require.Len(t, dest.Items, 1)
+	require.Equal(t, []string{"a", "b"}, dest.Items[0].Arguments())
+	require.Equal(t, "", dest.Items[0].Head())
+	require.Equal(t, 1, dest.Items[0].X)
+
+	var consumers []*headedMatcher
+	require.NoError(t, UnmarshalString("root {\n  path /a /b\n}", &consumers))
+	require.Len(t, consumers, 1)
+	require.Equal(t, []string{"path", "/a", "/b"}, consumers[0].Arguments())
+}
+
+func TestArgumentFields(t *testing.T) {
+	type (
+		upstream struct {
+			Host   string   `caddy:"host,arg=0"`
+			Port   uint16   `caddy:"port,arg=1"`
+			Tags   []string `caddy:"tags,arg=2..."`
+			Weight int      `caddy:"weight"`
+		}
+		server struct {
+			Args
+			Addr    HostPort      `caddy:",arg=0"`
+			Timeout time.Duration `caddy:",arg=1,optional"`
+		}
+		config struct {
+			Upstream upstream `caddy:"upstream"`
+			Servers  []server `caddy:"servers,heads"`
+		}
+		sample struct {
+			name     string
+			input    string
+			expected config
+			errMsg   string
+		}
+	)
+
+	samples := []sample{
+		{
+			name: "success",
+			input: `root {
+                        upstream example.com 8080 a b {
+                            weight 2
+                        }
+                        servers {
+                            server 10.0.0.1:80 5s
+                            server 10.0.0.2:80
+                        }
+                    }`,
+			expected: config{
+				Upstream: upstream{Host: "example.com", Port: 8080, Tags: []string{"a", "b"}, Weight: 2},
+				Servers: []server{
+					{
+						Args:    Args{head: "server", data: []string{"10.0.0.1:80", "5s"}},
+						Addr:    HostPort{Host: "10.0.0.1", Port: 80},
+						Timeout: 5 * time.Second,
+					},
+					{
+						Args: Args{head: "server", data: []string{"10.0.0.2:80"}},
+						Addr: HostPort{Host: "10.0.0.2", Port: 80},
+					},
+				},
+			},
+		},
+		{
+			name:     "no-block",
+			input:    "root {\n  upstream example.com 80\n}",
+			expected: config{Upstream: upstream{Host: "example.com", Port: 80}},
+		},
+		{
+			name:   "error-missing-argument",
+			input:  "root {\n  upstream example.com {\n  }\n}",
+			errMsg: "Caddyfile:2: unmarshal into caddycfg.upstream: missing argument 1 (Port)",
+		},
+		{
+			name:   "error-no-arguments",
+			input:  "root {\n  upstream\n}",
+			errMsg: "Caddyfile:2: unmarshal into caddycfg.upstream: missing argument 0 (Host)",
+		},
+		{
+			name:   "error-invalid-argument",
+			input:  "root {\n  upstream example.com 80x\n}",
+			errMsg: `Caddyfile:2: strconv.ParseUint: parsing "80x": invalid syntax`,
+		},
+		{
+			name:   "error-extra-argument",
+			input:  "root {\n  servers {\n    server 10.0.0.1:80 5s\n    server 10.0.0.2:80 5s 10s\n  }\n}",
+			errMsg: "Caddyfile:4: unmarshal into caddycfg.server: unexpected argument 10s",
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			var got config
+			err := UnmarshalString(s.input, &got)
+			if len(s.errMsg) > 0 {
+				require.EqualError(t, err, s.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, s.expected, got)
+		})
+	}
+
+	var te *TokenError
+	err := UnmarshalString("root {\n  upstream example.com 80x\n}", &config{})
+	require.True(t, errors.As(err, &te))
+	require.Equal(t, "Upstream.Port", te.Path)
+	require.Equal(t, "80x", te.Value)
+
+	var routes struct {
+		Routes []struct {
+			Kind string `caddy:"kind,head"`
+			Path string `caddy:"path,arg=0"`
+		} `caddy:"routes"`
+	}
+	require.NoError(t, UnmarshalString("root {\n  routes {\n    route /api\n    redir /old\n  }\n}", &routes))
+	require.Len(t, routes.Routes, 2)
+	require.Equal(t, "route", routes.Routes[0].Kind)
+	require.Equal(t, "/api", routes.Routes[0].Path)
+	require.Equal(t, "redir", routes.Routes[1].Kind)
+
+	var badHead struct {
+		Kind int    `caddy:"kind,head"`
+		Path string `caddy:"path,arg=0"`
+	}
+	err = UnmarshalString("root /api", &badHead)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "keeps the head and must be a string")
+
+	var gap struct {
+		A int `caddy:"a,arg=0"`
+		B int `caddy:"b,arg=2"`
+	}
+	err = UnmarshalString("root 1 2", &gap)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is bound to argument 2, argument 1 is not bound")
+
+	var variadic struct {
+		A int `caddy:"a,arg=0..."`
+	}
+	err = UnmarshalString("root 1 2", &variadic)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "takes the rest of arguments and must be a slice")
+}
+
+func TestFlagKeys(t *testing.T) {
+	type config struct {
+		Gzip     bool  `caddy:"gzip"`
+		Insecure *bool `caddy:"insecure_skip_verify"`
+		Port     int   `caddy:"port"`
+	}
+
+	var dest config
+	require.NoError(t, UnmarshalString("root {\n  gzip\n  insecure_skip_verify\n  port 80\n}", &dest))
+	require.True(t, dest.Gzip)
+	require.True(t, *dest.Insecure)
+	require.Equal(t, 80, dest.Port)
+
+	decode := func(input string, opts ...Option) error {
+		tokens, err := tokenize("Caddyfile", []byte(input))
+		require.NoError(t, err)
+		return NewTokensDecoder(tokens, opts...).Decode(&dest)
+	}
+
+	dest = config{}
+	require.NoError(t, decode("root {\n  gzip on\n  insecure_skip_verify 0\n}", AllowBoolAliases(true)))
+	require.True(t, dest.Gzip)
+	require.False(t, *dest.Insecure)
+
+	require.EqualError(t, decode("root {\n  gzip\n}", RequireBoolValues(true)), "Caddyfile:1: got no data for bool")
+
+	// a token left after a value is not a flag key
+	var leftover struct {
+		N int  `caddy:"n"`
+		G bool `caddy:"g"`
+	}
+	err := UnmarshalString("root {\n  n 1 g\n}", &leftover)
+	require.EqualError(t, err, "Caddyfile:2: unexpected data 'g' after the value of n")
+	require.True(t, errors.Is(err, ErrUnexpectedData))
+	var te *TokenError
+	require.True(t, errors.As(err, &te))
+	require.Equal(t, 7, te.Col)
+	require.Equal(t, "N", te.Path)
+
+	// only a key starting its line can be a flag
+	err = UnmarshalString("root { g\n}", &leftover)
+	require.EqualError(t, err, "Caddyfile:1: got no data for bool")
+
+	var flags map[string]bool
+	err = UnmarshalString("root {\n  a true b\n}", &flags)
+	require.EqualError(t, err, "Caddyfile:2: unexpected data 'b' after the value of a")
+	require.True(t, errors.As(err, &te))
+	require.Equal(t, `["a"]`, te.Path)
+}
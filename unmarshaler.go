@@ -0,0 +1,37 @@
+package caddycfg
+
+import (
+	"reflect"
+)
+
+var caddyUnmarshalerType = reflect.TypeOf((*CaddyUnmarshaler)(nil)).Elem()
+
+// CaddyUnmarshaler is implemented by types decoding themselves from config tokens. UnmarshalCaddy is called with the
+// head of an entry (a key or a plugin name) consumed already and must consume the rest of the entry: arguments of
+// the head line and a block if there is one. Use UnmarshalStream with s to hand sub-values back to the decoder, they
+// are decoded with options of the current decoding then
+type CaddyUnmarshaler interface {
+	UnmarshalCaddy(head Token, s Stream) error
+}
+
+// unmarshalerStream stream given to CaddyUnmarshaler, it lets UnmarshalStream proceed with the current decoding
+type unmarshalerStream struct {
+	Stream
+	c *caddyCfgUnmarshaler
+}
+
+func (c *caddyCfgUnmarshaler) processCaddyUnmarshaler(head Token, s Stream, v reflect.Value) error {
+	r := unmarshalerValue(v, caddyUnmarshalerType).Interface().(CaddyUnmarshaler)
+	err := r.UnmarshalCaddy(head, &unmarshalerStream{Stream: s, c: c})
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*TokenError); ok {
+		return err
+	}
+	return &TokenError{
+		Token: head,
+		Kind:  KindCustom,
+		Err:   err,
+	}
+}
@@ -0,0 +1,198 @@
+package caddycfg
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy"
+	"github.com/stretchr/testify/require"
+)
+
+type healthCheck struct {
+	Path     string `json:"path"`
+	Interval int    `json:"interval"`
+}
+
+// backend decodes `backend address [weight N] { health { … } header name value }` itself
+type backend struct {
+	Address string
+	Weight  int
+	Health  healthCheck
+	Headers map[string]string
+}
+
+var errNoAddress = errors.New("backend address expected")
+
+func (b *backend) UnmarshalCaddy(head Token, s Stream) error {
+	if !s.NextArg() {
+		return errNoAddress
+	}
+	b.Address = s.Token().Value
+	s.Confirm()
+
+	for s.NextArg() && s.Token().Value == "weight" {
+		s.Confirm()
+		if !s.NextArg() {
+			return TokenErrorf(head, "weight value expected")
+		}
+		weight, err := strconv.Atoi(s.Token().Value)
+		if err != nil {
+			return TokenErrorf(s.Token(), "invalid weight: %s", err)
+		}
+		b.Weight = weight
+		s.Confirm()
+	}
+	if !s.NextArg() {
+		return nil
+	}
+	if s.Token().Value != "{" {
+		return TokenErrorf(s.Token(), "unexpected %s", s.Token())
+	}
+	s.Confirm()
+
+	for s.Next() {
+		t := s.Token()
+		s.Confirm()
+		switch t.Value {
+		case "}":
+			return nil
+		case "health":
+			if err := UnmarshalStream(t, s, &b.Health); err != nil {
+				return err
+			}
+		case "header":
+			var args []string
+			if err := UnmarshalStream(t, s, &args); err != nil {
+				return err
+			}
+			if len(args) != 2 {
+				return TokenErrorf(t, "header name and value expected")
+			}
+			if b.Headers == nil {
+				b.Headers = map[string]string{}
+			}
+			b.Headers[args[0]] = args[1]
+		default:
+			return TokenErrorf(t, "unknown backend option %s", t)
+		}
+	}
+	return TokenErrorf(head, "unclosed block")
+}
+
+func TestCaddyUnmarshaler(t *testing.T) {
+	type (
+		config struct {
+			Primary backend  `json:"primary"`
+			Backup  *backend `json:"backup"`
+			Name    string   `json:"name"`
+		}
+		sample struct {
+			name     string
+			input    string
+			options  []Option
+			expected config
+			errMsg   string
+			errIs    error
+		}
+	)
+
+	samples := []sample{
+		{
+			name: "success",
+			input: `root {
+                        primary 10.0.0.1:80 weight 3 {
+                            header X-A a
+                            health {
+                                PATH /health
+                                interval 5
+                            }
+                            header X-B b
+                        }
+                        backup 10.0.0.2:80
+                        name proxy
+                    }`,
+			options: []Option{KeyNormalizer(strings.ToLower)},
+			expected: config{
+				Primary: backend{
+					Address: "10.0.0.1:80",
+					Weight:  3,
+					Health:  healthCheck{Path: "/health", Interval: 5},
+					Headers: map[string]string{"X-A": "a", "X-B": "b"},
+				},
+				Backup: &backend{Address: "10.0.0.2:80"},
+				Name:   "proxy",
+			},
+		},
+		{
+			name: "error-sub-value",
+			input: `root {
+                        primary 10.0.0.1:80 {
+                            health {
+                                interval often
+                            }
+                        }
+                    }`,
+			errMsg: `Testfile:4: strconv.Atoi: parsing "often": invalid syntax`,
+		},
+		{
+			name: "error-custom",
+			input: `root {
+                        primary 10.0.0.1:80 weight x
+                    }`,
+			errMsg: `Testfile:2: invalid weight: strconv.Atoi: parsing "x": invalid syntax`,
+		},
+		{
+			name: "error-plain",
+			input: `root {
+                        primary
+                    }`,
+			errMsg: "Testfile:2: backend address expected",
+			errIs:  errNoAddress,
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			c := caddy.NewTestController("http", s.input)
+			var cfg config
+			err := NewDecoder(c, s.options...).Decode(&cfg)
+			if len(s.errMsg) > 0 {
+				require.EqualError(t, err, s.errMsg)
+				if s.errIs != nil {
+					require.ErrorIs(t, err, s.errIs)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, s.expected, cfg)
+		})
+	}
+}
+
+func TestCaddyUnmarshalerCollectErrors(t *testing.T) {
+	type config struct {
+		Primary backend `json:"primary"`
+		Port    int     `json:"port"`
+	}
+	c := caddy.NewTestController("http", `root {
+        primary 10.0.0.1:80 {
+            health {
+                interval often
+                path /health
+                timeout 1
+            }
+        }
+        port x
+    }`)
+	var cfg config
+	err := NewDecoder(c, CollectErrors(true)).Decode(&cfg)
+	var multi MultiError
+	require.True(t, errors.As(err, &multi))
+	require.Len(t, multi, 3)
+	require.ErrorIs(t, multi[0], ErrInvalidValue)
+	require.ErrorIs(t, multi[1], ErrUnknownKey)
+	require.ErrorIs(t, multi[2], ErrInvalidValue)
+	require.Equal(t, "/health", cfg.Primary.Health.Path)
+}
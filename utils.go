@@ -1,35 +1,50 @@
 package caddycfg
 
 import (
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 )
 
-type tokenError struct {
-	Token
-	err error
-}
-
-// Error ...
-func (te tokenError) Error() string {
-	return fmt.Sprintf("%s:%d: %s", te.File, te.Lin, te.err)
-}
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
 
-// TokenError diagnose a error caused with a given token
-func TokenError(t Token, err error) error {
-	return tokenError{
-		Token: t,
-		err:   err,
+// implementsUnmarshaler checks if t or a pointer to t implements iface, pointer types are dereferenced to check
+func implementsUnmarshaler(t reflect.Type, iface reflect.Type) bool {
+	for {
+		if t.Kind() == reflect.Interface {
+			return false
+		}
+		if t.Implements(iface) || reflect.PtrTo(t).Implements(iface) {
+			return true
+		}
+		if t.Kind() != reflect.Ptr {
+			return false
+		}
+		t = t.Elem()
 	}
 }
 
-// TokenErrorf diagnose a error caused with a given token with custom error message
-func TokenErrorf(t Token, format string, a ...interface{}) error {
-	err := fmt.Errorf(format, a...)
-	return TokenError(t, err)
+// unmarshalerValue allocates pointers of v until it reaches the one implementing iface. A pointer to v itself
+// is returned, with the value reset, if there's no such pointer
+func unmarshalerValue(v reflect.Value, iface reflect.Type) reflect.Value {
+	for {
+		if v.Kind() != reflect.Ptr {
+			v.Set(reflect.Zero(v.Type()))
+			return v.Addr()
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		if v.Type().Implements(iface) {
+			return v
+		}
+		v = v.Elem()
+	}
 }
 
 func ref(v reflect.Value) reflect.Value {
@@ -58,13 +73,16 @@ func refType(t reflect.Type) (reflect.Type, bool) {
 	return t, false
 }
 
-func createStructIndex(index map[string][]int, v reflect.Value, prefix []int) error {
+func createStructIndex(index map[string][]int, v reflect.Value, prefix []int, tagName string) error {
 	t := v.Type()
 	for i := 0; i < v.NumField(); i++ {
 		field := t.Field(i)
 
 		if field.Type.Kind() == reflect.Struct && field.Anonymous {
-			if err := createStructIndex(index, v.Field(i), append(prefix, i)); err != nil {
+			if tag, ok := lookupFieldTag(field, tagName); ok && tag.skip {
+				continue
+			}
+			if err := createStructIndex(index, v.Field(i), append(prefix, i), tagName); err != nil {
 				return err
 			}
 			continue
@@ -76,20 +94,162 @@ func createStructIndex(index map[string][]int, v reflect.Value, prefix []int) er
 			}
 		}
 
-		name, ok := field.Tag.Lookup("json")
+		tag, ok := lookupFieldTag(field, tagName)
 		if !ok {
-			return fmt.Errorf("field '%s' from %s doesn't have 'json' tag", field.Name, v.Field(i).Type())
+			return fmt.Errorf("field '%s' from %s has neither '%s' nor 'json' tag", field.Name, v.Field(i).Type(), tagName)
 		}
-		if _, ok := index[name]; ok {
-			return fmt.Errorf("field '%s' from %s has duplicate json tag value '%s'", field.Name, v.Field(i).Type(), name)
+		if tag.skip || tag.has("arg") || tag.has("head") {
+			continue
+		}
+		if len(tag.name) == 0 {
+			return fmt.Errorf("field '%s' from %s has no key name in its tag", field.Name, v.Field(i).Type())
+		}
+		if _, ok := index[tag.name]; ok {
+			return fmt.Errorf("field '%s' from %s has duplicate tag value '%s'", field.Name, v.Field(i).Type(), tag.name)
+		}
+		ppp := make([]int, len(prefix), len(prefix)+1)
+		copy(ppp, prefix)
+		index[tag.name] = append(ppp, i)
+	}
+	return nil
+}
+
+// argField a struct field bound to a positional argument with arg=N tag option, or to arguments starting from N with
+// arg=N... one
+type argField struct {
+	index    []int
+	pos      int
+	variadic bool
+}
+
+// createArgIndex collects fields bound to positional arguments ordered by their positions. Positions must go one after
+// another starting from 0, a variadic field must be a slice taking the last position
+func createArgIndex(t reflect.Type, tagName string) ([]argField, error) {
+	var fields []argField
+	if err := collectArgFields(&fields, t, nil, tagName); err != nil {
+		return nil, err
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].pos < fields[j].pos
+	})
+	for i, field := range fields {
+		structField := t.FieldByIndex(field.index)
+		if field.pos != i {
+			return nil, fmt.Errorf("field '%s' from %s is bound to argument %d, argument %d is not bound", structField.Name, t, field.pos, i)
+		}
+		if !field.variadic {
+			continue
+		}
+		if i != len(fields)-1 {
+			return nil, fmt.Errorf("field '%s' from %s takes the rest of arguments and must be the last one", structField.Name, t)
+		}
+		ft := structField.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("field '%s' from %s takes the rest of arguments and must be a slice", structField.Name, t)
+		}
+	}
+	return fields, nil
+}
+
+func collectArgFields(fields *[]argField, t reflect.Type, prefix []int, tagName string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := lookupFieldTag(field, tagName)
+		if ok && tag.skip {
+			continue
 		}
 		ppp := make([]int, len(prefix), len(prefix)+1)
 		copy(ppp, prefix)
-		index[name] = append(ppp, i)
+		ppp = append(ppp, i)
+
+		if field.Type.Kind() == reflect.Struct && field.Anonymous {
+			if err := collectArgFields(fields, field.Type, ppp, tagName); err != nil {
+				return err
+			}
+			continue
+		}
+		if !ok || !tag.has("arg") || field.PkgPath != "" {
+			continue
+		}
+
+		value := tag.options["arg"]
+		variadic := strings.HasSuffix(value, "...")
+		pos, err := strconv.Atoi(strings.TrimSuffix(value, "..."))
+		if err != nil || pos < 0 {
+			return fmt.Errorf("field '%s' from %s has invalid arg tag option value '%s'", field.Name, t, value)
+		}
+		for _, f := range *fields {
+			if f.pos == pos {
+				return fmt.Errorf("field '%s' from %s is bound to argument %d taken by another field", field.Name, t, pos)
+			}
+		}
+		*fields = append(*fields, argField{
+			index:    ppp,
+			pos:      pos,
+			variadic: variadic,
+		})
 	}
 	return nil
 }
 
+// findHeadField looks for a string field tagged with head option, it keeps the head of a slice item. Returns nil if
+// there is no such field
+func findHeadField(t reflect.Type, tagName string) ([]int, error) {
+	var res []int
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := lookupFieldTag(field, tagName)
+		if ok && tag.skip {
+			continue
+		}
+
+		var index []int
+		switch {
+		case field.Type.Kind() == reflect.Struct && field.Anonymous:
+			sub, err := findHeadField(field.Type, tagName)
+			if err != nil {
+				return nil, err
+			}
+			if sub == nil {
+				continue
+			}
+			index = append([]int{i}, sub...)
+		case ok && tag.has("head") && field.PkgPath == "":
+			if field.Type.Kind() != reflect.String {
+				return nil, fmt.Errorf("field '%s' from %s keeps the head and must be a string", field.Name, t)
+			}
+			index = []int{i}
+		default:
+			continue
+		}
+		if res != nil {
+			return nil, fmt.Errorf("%s has several fields keeping the head", t)
+		}
+		res = index
+	}
+	return res, nil
+}
+
+// fieldTagByIndex returns a tag of the field with the given index
+func fieldTagByIndex(t reflect.Type, index []int, tagName string) fieldTag {
+	tag, _ := lookupFieldTag(t.FieldByIndex(index), tagName)
+	return tag
+}
+
+// fieldPath returns Go path of the field with the given index, e.g. Embedded.Field
+func fieldPath(t reflect.Type, index []int) string {
+	names := make([]string, len(index))
+	for i, fieldIndex := range index {
+		field := t.Field(fieldIndex)
+		names[i] = field.Name
+		t = field.Type
+	}
+	return strings.Join(names, ".")
+}
+
 func orderFields(index map[string][]int) []string {
 	names := make([]string, len(index))
 	i := 0
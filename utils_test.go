@@ -98,6 +98,20 @@ func TestCreateStructIndex(t *testing.T) {
 			s:       head{},
 			wantErr: false,
 		},
+		{
+			name: "caddy-tag-success",
+			expected: map[string][]int{
+				"a": {0},
+				"b": {1},
+			},
+			s: struct {
+				A int `caddy:"a" json:"json_a"`
+				B int `json:"b"`
+				C int `caddy:"-" json:"c"`
+				D int `json:"-"`
+			}{},
+			wantErr: false,
+		},
 		{
 			name: "error-no-json-tag",
 			s: struct {
@@ -125,7 +139,7 @@ func TestCreateStructIndex(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			index := map[string][]int{}
-			if err := createStructIndex(index, reflect.ValueOf(tt.s), []int{}); (err != nil) != tt.wantErr {
+			if err := createStructIndex(index, reflect.ValueOf(tt.s), []int{}, defaultTagName); (err != nil) != tt.wantErr {
 				t.Errorf("createStructIndex() error = %v, wantErr %v", err, tt.wantErr)
 			} else if err == nil {
 				assert.Equal(t, tt.expected, index)